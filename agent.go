@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentProtocolVersion is sent in every message, and bumped when a change
+// would make older clients misread the samples.
+const agentProtocolVersion = 1
+
+// agentDefaultAddress is where --agent listens when --listen isn't given.
+const agentDefaultAddress = "localhost:9977"
+
+// agentMessage is one line of the agent's wire format. Right after a client
+// connects, the agent sends a "static" message, followed by a "sample"
+// message every refresh tick:
+//
+//	{"version":1,"type":"static","static":{"Hostname":"buildbox",...}}
+//	{"version":1,"type":"sample","sample":{"Time":"...","CPUTotal":12.5,...}}
+//
+// The fields of "static" and "sample" are the ones of StaticInfo and Sample.
+type agentMessage struct {
+	Version int         `json:"version"`
+	Type    string      `json:"type"`
+	Static  *StaticInfo `json:"static,omitempty"`
+	Sample  *Sample     `json:"sample,omitempty"`
+}
+
+// sampleSource is where the dashboard gets its samples from: this machine
+// (collector) or a TermiDash agent (remoteSource). The sample returned with an
+// error may be nil, or the last one received before the error.
+type sampleSource interface {
+	Collect() (*Sample, error)
+}
+
+// agentAddress splits "unix:/path/to.sock" or "host:port" into the network
+// and the address to give to net.Listen or net.Dial.
+func agentAddress(address string) (string, string) {
+	if path, found := strings.CutPrefix(address, "unix:"); found {
+		return "unix", path
+	}
+	return "tcp", address
+}
+
+// runAgent serves the samples of source, normally this machine's, to every
+// client connected to address, until it fails to listen.
+func runAgent(address string, staticInfo *StaticInfo, source sampleSource, interval time.Duration) error {
+	network, listenAddress := agentAddress(address)
+	if network == "unix" {
+		// A socket file left by a previous agent would make Listen fail.
+		os.Remove(listenAddress)
+	}
+	listener, err := net.Listen(network, listenAddress)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "TermiDash: agent listening on %s %s\n", network, listener.Addr())
+	serveAgent(listener, staticInfo, source, interval)
+	return nil
+}
+
+// agentClientBuffer is how many lines a client can be behind before the agent
+// drops it.
+const agentClientBuffer = 4
+
+// agentClient is a connection to a client of the agent, written by its own
+// goroutine so that a stalled client doesn't hold back the others.
+type agentClient struct {
+	conn  net.Conn
+	lines chan []byte
+	done  chan struct{} // closed once the writing stopped
+}
+
+// newAgentClient starts writing to conn, beginning with the first lines.
+func newAgentClient(conn net.Conn, first ...[]byte) *agentClient {
+	client := &agentClient{conn: conn, lines: make(chan []byte, agentClientBuffer), done: make(chan struct{})}
+	for _, line := range first {
+		client.lines <- line
+	}
+	go client.write()
+	return client
+}
+
+func (client *agentClient) write() {
+	defer exitOnPanic()
+	defer close(client.done)
+	defer client.conn.Close()
+	for line := range client.lines {
+		client.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := client.conn.Write(line); err != nil {
+			return
+		}
+	}
+}
+
+// Send queues a line for the client. It returns false when the client is
+// gone, or too far behind and must be closed.
+func (client *agentClient) Send(line []byte) bool {
+	select {
+	case <-client.done:
+		return false
+	default:
+	}
+	select {
+	case client.lines <- line:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops the writing, even when blocked on a stalled client.
+func (client *agentClient) Close() {
+	close(client.lines)
+	client.conn.Close()
+}
+
+// serveAgent sends the static information, then a sample of source every
+// interval, to every client connecting to listener. It returns, closing the
+// clients' connections, once the listener is closed.
+func serveAgent(listener net.Listener, staticInfo *StaticInfo, source sampleSource, interval time.Duration) {
+	defer listener.Close()
+	staticLine, _ := json.Marshal(agentMessage{Version: agentProtocolVersion, Type: "static", Static: staticInfo})
+	staticLine = append(staticLine, '\n')
+	var mu sync.Mutex
+	clients := make(map[*agentClient]bool)
+	var lastSampleLine []byte
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var delay time.Duration
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				// E.g. too many open files: wait a little before trying
+				// again, longer each time, instead of spinning.
+				delay = min(max(2*delay, 5*time.Millisecond), time.Second)
+				time.Sleep(delay)
+				continue
+			}
+			delay = 0
+			mu.Lock()
+			if lastSampleLine != nil {
+				clients[newAgentClient(conn, staticLine, lastSampleLine)] = true
+			} else {
+				clients[newAgentClient(conn, staticLine)] = true
+			}
+			mu.Unlock()
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sample, _ := source.Collect()
+		line, err := json.Marshal(agentMessage{Version: agentProtocolVersion, Type: "sample", Sample: sample})
+		if err == nil {
+			line = append(line, '\n')
+			mu.Lock()
+			lastSampleLine = line
+			for client := range clients {
+				if !client.Send(line) {
+					client.Close()
+					delete(clients, client)
+				}
+			}
+			mu.Unlock()
+		}
+		select {
+		case <-ticker.C:
+		case <-closed:
+			mu.Lock()
+			for client := range clients {
+				client.Close()
+			}
+			mu.Unlock()
+			return
+		}
+	}
+}
+
+// remoteSource receives the samples of a TermiDash agent. It reconnects on
+// its own when the connection is lost.
+type remoteSource struct {
+	address string
+
+	mu       sync.Mutex
+	static   *StaticInfo
+	latest   *Sample
+	received time.Time
+	err      error
+	conn     net.Conn
+
+	staticReady chan struct{}
+	once        sync.Once
+	closing     chan struct{}
+	closed      chan struct{}
+}
+
+func newRemoteSource(address string) *remoteSource {
+	remote := &remoteSource{
+		address:     address,
+		staticReady: make(chan struct{}),
+		closing:     make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	go remote.run()
+	return remote
+}
+
+// run connects to the agent and reads its messages, reconnecting with a
+// growing delay (up to 30s) whenever the connection fails, until Close.
+func (remote *remoteSource) run() {
+	defer exitOnPanic()
+	defer close(remote.closed)
+	delay := time.Second
+	for {
+		connected, err := remote.receive()
+		remote.mu.Lock()
+		remote.err = err
+		remote.mu.Unlock()
+		if connected {
+			delay = time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-remote.closing:
+			return
+		}
+		if delay < 30*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// Close disconnects from the agent and stops reconnecting.
+func (remote *remoteSource) Close() {
+	remote.mu.Lock()
+	select {
+	case <-remote.closing:
+	default:
+		close(remote.closing)
+		if remote.conn != nil {
+			remote.conn.Close()
+		}
+	}
+	remote.mu.Unlock()
+	<-remote.closed
+}
+
+// receive reads the messages of one connection until it fails. connected
+// tells whether the agent could be reached at all.
+func (remote *remoteSource) receive() (connected bool, err error) {
+	network, address := agentAddress(remote.address)
+	conn, err := net.DialTimeout(network, address, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	remote.mu.Lock()
+	select {
+	case <-remote.closing:
+		remote.mu.Unlock()
+		return true, net.ErrClosed
+	default:
+		remote.conn = conn
+	}
+	remote.mu.Unlock()
+	reader := bufio.NewReader(conn)
+	for {
+		// The agent sends a sample at least every minute, a longer silence
+		// means the connection is dead even if it wasn't closed.
+		conn.SetReadDeadline(time.Now().Add(2 * time.Minute))
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return true, err
+		}
+		var message agentMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			return true, fmt.Errorf("invalid message from the agent: %w", err)
+		}
+		if message.Version != agentProtocolVersion {
+			return true, fmt.Errorf("the agent speaks version %d of the protocol, not %d", message.Version, agentProtocolVersion)
+		}
+		remote.mu.Lock()
+		switch message.Type {
+		case "static":
+			remote.static = message.Static
+			remote.once.Do(func() { close(remote.staticReady) })
+		case "sample":
+			remote.latest = message.Sample
+			remote.received = time.Now()
+		}
+		remote.err = nil
+		remote.mu.Unlock()
+	}
+}
+
+// Static waits for the static information of the agent.
+func (remote *remoteSource) Static(timeout time.Duration) (*StaticInfo, error) {
+	select {
+	case <-remote.staticReady:
+		remote.mu.Lock()
+		defer remote.mu.Unlock()
+		if remote.static == nil {
+			return nil, fmt.Errorf(tr("the agent at %s sent no system information"), remote.address)
+		}
+		return remote.static, nil
+	case <-time.After(timeout):
+		remote.mu.Lock()
+		defer remote.mu.Unlock()
+		if remote.err != nil {
+			return nil, fmt.Errorf(tr("can't connect to the agent at %s: %w"), remote.address, remote.err)
+		}
+		return nil, fmt.Errorf(tr("no answer from the agent at %s"), remote.address)
+	}
+}
+
+// Latest returns the last sample received from the agent, when it was
+// received, and the connection's error if it is currently down.
+func (remote *remoteSource) Latest() (*Sample, time.Time, error) {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	return remote.latest, remote.received, remote.err
+}
+
+// Collect returns the last sample received from the agent.
+func (remote *remoteSource) Collect() (*Sample, error) {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	if remote.err != nil {
+		return remote.latest, fmt.Errorf(tr("connection to %s lost (%v), reconnecting"), remote.address, remote.err)
+	}
+	if remote.latest == nil {
+		return nil, fmt.Errorf(tr("waiting for the first sample from %s"), remote.address)
+	}
+	return remote.latest, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// waitFor polls condition until it is true, failing the test after 5s.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAgentRoundTrip(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticInfo := fakeStaticInfo()
+	served := make(chan struct{})
+	go func() {
+		serveAgent(listener, &staticInfo, newFakeSource(3), 20*time.Millisecond)
+		close(served)
+	}()
+
+	remote := newRemoteSource(listener.Addr().String())
+	defer remote.Close()
+	remoteInfo, err := remote.Static(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteInfo.Hostname != staticInfo.Hostname || remoteInfo.CPUModel != staticInfo.CPUModel || !remoteInfo.Pressure {
+		t.Errorf("static information %+v, want the one of %s", remoteInfo, staticInfo.Hostname)
+	}
+
+	// The fake source's load rises at each step, up to its third sample.
+	last := fakeSample(2)
+	waitFor(t, "the samples to stream", func() bool {
+		sample, err := remote.Collect()
+		return err == nil && sample.CPUTotal == last.CPUTotal
+	})
+	sample, _ := remote.Collect()
+	if !sample.Time.Equal(last.Time) || len(sample.Disks) != len(last.Disks) || sample.Pressure["io"] != last.Pressure["io"] {
+		t.Errorf("sample %+v, want %+v", sample, last)
+	}
+
+	listener.Close()
+	<-served
+	waitFor(t, "the disconnection", func() bool {
+		_, err := remote.Collect()
+		return err != nil
+	})
+	sample, err = remote.Collect()
+	if sample == nil || sample.CPUTotal != last.CPUTotal {
+		t.Errorf("after the disconnection, got sample %v, want the last one received", sample)
+	}
+	if _, _, latestErr := remote.Latest(); latestErr == nil {
+		t.Errorf("Latest gave no error after the disconnection (Collect: %v)", err)
+	}
+}
+
+func TestAgentWithoutStatic(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(`{"version":1,"type":"static"}` + "\n"))
+		time.Sleep(time.Second)
+	}()
+
+	remote := newRemoteSource(listener.Addr().String())
+	defer remote.Close()
+	remoteInfo, err := remote.Static(5 * time.Second)
+	if err == nil || remoteInfo != nil {
+		t.Errorf("Static gave %v, %v, want an error", remoteInfo, err)
+	}
+}
+
+func TestAgentClientStalled(t *testing.T) {
+	// Nothing reads the end of the pipe: every write blocks.
+	stalled, stalledPeer := net.Pipe()
+	defer stalledPeer.Close()
+	reading, readingPeer := net.Pipe()
+	defer readingPeer.Close()
+	received := make(chan string, 4*agentClientBuffer)
+	go func() {
+		scanner := bufio.NewScanner(readingPeer)
+		for scanner.Scan() {
+			received <- scanner.Text()
+		}
+	}()
+
+	next := func() {
+		t.Helper()
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the reading client got no line")
+		}
+	}
+
+	slow, fast := newAgentClient(stalled, []byte("static\n")), newAgentClient(reading, []byte("static\n"))
+	defer fast.Close()
+	next()
+	start := time.Now()
+	dropped := false
+	for range 2 * agentClientBuffer {
+		if !dropped && !slow.Send([]byte("sample\n")) {
+			slow.Close()
+			dropped = true
+		}
+		if !fast.Send([]byte("sample\n")) {
+			t.Fatal("the reading client was dropped")
+		}
+		next()
+	}
+	if !dropped {
+		t.Error("the stalled client was kept")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sending took %v with a stalled client", elapsed)
+	}
+	<-slow.done
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AlertActionSettings is the [AlertActions] table of the config file. It
+// applies to the Command and Webhook of every alert rule.
+type AlertActionSettings struct {
+	Retries       int           `toml:"Retries"`
+	RetryDelay    time.Duration `toml:"RetryDelay"`
+	MinInterval   time.Duration `toml:"MinInterval"`
+	Timeout       time.Duration `toml:"Timeout"`
+	NotifyResolve bool          `toml:"NotifyResolve"`
+}
+
+var defaultAlertActionSettings = AlertActionSettings{
+	Retries:       3,
+	RetryDelay:    2 * time.Second,
+	MinInterval:   5 * time.Minute,
+	Timeout:       10 * time.Second,
+	NotifyResolve: true,
+}
+
+// alertPayload is the JSON body POSTed to webhooks. The same fields are given
+// to commands as TERMIDASH_ALERT_* environment variables.
+type alertPayload struct {
+	Name       string  `json:"name"`
+	Rule       string  `json:"rule"`
+	State      string  `json:"state"`
+	Value      float64 `json:"value"`
+	Panel      string  `json:"panel"`
+	Host       string  `json:"host"`
+	Time       string  `json:"time"`
+	Suppressed int     `json:"suppressed"`
+}
+
+// alertNotifier runs the Command and Webhook actions of alert rules. Actions
+// run in their own goroutine, so a slow webhook never holds the refresh back,
+// and failures are reported on the Failures channel.
+type alertNotifier struct {
+	settings AlertActionSettings
+	hostname string
+	client   *http.Client
+	Failures chan string
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	sentFiring map[string]bool
+	suppressed map[string]int
+}
+
+func newAlertNotifier(settings AlertActionSettings, hostname string) *alertNotifier {
+	return &alertNotifier{
+		settings:   settings,
+		hostname:   hostname,
+		client:     &http.Client{Timeout: settings.Timeout},
+		Failures:   make(chan string, 16),
+		lastSent:   make(map[string]time.Time),
+		sentFiring: make(map[string]bool),
+		suppressed: make(map[string]int),
+	}
+}
+
+// Notify starts the actions of the event's rule. A rule fires its actions at
+// most once per MinInterval, and a resolve notification is only sent for an
+// alert whose firing notification went out.
+func (notifier *alertNotifier) Notify(event AlertEvent) {
+	if event.rule == nil || (event.rule.Command == "" && event.rule.Webhook == "") {
+		return
+	}
+	key := event.Name + "\x00" + event.Rule
+	notifier.mu.Lock()
+	if event.Resolved {
+		if !notifier.settings.NotifyResolve || !notifier.sentFiring[key] {
+			notifier.mu.Unlock()
+			return
+		}
+		notifier.sentFiring[key] = false
+	} else {
+		if last, ok := notifier.lastSent[key]; ok && event.Time.Sub(last) < notifier.settings.MinInterval {
+			notifier.suppressed[key]++
+			notifier.mu.Unlock()
+			return
+		}
+		notifier.lastSent[key] = event.Time
+		notifier.sentFiring[key] = true
+	}
+	payload := alertPayload{
+		Name:       event.Name,
+		Rule:       event.Rule,
+		State:      "firing",
+		Value:      event.Value,
+		Panel:      event.Panel,
+		Host:       notifier.hostname,
+		Time:       event.Time.Format(time.RFC3339),
+		Suppressed: notifier.suppressed[key],
+	}
+	if event.Resolved {
+		payload.State = "resolved"
+	} else {
+		notifier.suppressed[key] = 0
+	}
+	notifier.mu.Unlock()
+
+	if event.rule.Command != "" {
+		go notifier.retry("command for "+event.Name, func() error {
+			return notifier.runCommand(event.rule.Command, payload)
+		})
+	}
+	if event.rule.Webhook != "" {
+		go notifier.retry("webhook for "+event.Name, func() error {
+			return notifier.postWebhook(event.rule.Webhook, payload)
+		})
+	}
+}
+
+func (notifier *alertNotifier) retry(what string, action func() error) {
+	defer exitOnPanic()
+	delay := notifier.settings.RetryDelay
+	var err error
+	for attempt := 0; attempt <= notifier.settings.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = action(); err == nil {
+			return
+		}
+	}
+	select {
+	case notifier.Failures <- fmt.Sprintf(tr("Alert %s failed: %v"), what, err):
+	default:
+	}
+}
+
+func (notifier *alertNotifier) runCommand(command string, payload alertPayload) error {
+	ctx := context.Background()
+	if notifier.settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, notifier.settings.Timeout)
+		defer cancel()
+	}
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(),
+		"TERMIDASH_ALERT_NAME="+payload.Name,
+		"TERMIDASH_ALERT_RULE="+payload.Rule,
+		"TERMIDASH_ALERT_STATE="+payload.State,
+		"TERMIDASH_ALERT_VALUE="+strconv.FormatFloat(payload.Value, 'f', 2, 64),
+		"TERMIDASH_ALERT_PANEL="+payload.Panel,
+		"TERMIDASH_ALERT_HOST="+payload.Host,
+		"TERMIDASH_ALERT_TIME="+payload.Time,
+		"TERMIDASH_ALERT_SUPPRESSED="+strconv.Itoa(payload.Suppressed),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+	}
+	return err
+}
+
+// shellCommand runs command through the system's shell, so that pipes and
+// variables work in the config file's commands. When the context is done,
+// the shell is killed and its children get a second to close their output.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+func (notifier *alertNotifier) postWebhook(url string, payload alertPayload) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(payload); err != nil {
+		return err
+	}
+	response, err := notifier.client.Post(url, "application/json", &body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRequest is a request received by a test webhook.
+type webhookRequest struct {
+	received    time.Time
+	contentType string
+	payload     alertPayload
+}
+
+// startWebhook runs a webhook answering with the given statuses in turn, then
+// 200 OK, and sends every request it receives on the returned channel.
+func startWebhook(t *testing.T, statuses ...int) (string, chan webhookRequest) {
+	t.Helper()
+	requests := make(chan webhookRequest, 16)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := webhookRequest{received: time.Now(), contentType: r.Header.Get("Content-Type")}
+		if r.Method != http.MethodPost {
+			t.Errorf("got a %s request, want POST", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request.payload); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+		requests <- request
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, requests
+}
+
+// nextRequest waits for the next request of a test webhook.
+func nextRequest(t *testing.T, requests chan webhookRequest) webhookRequest {
+	t.Helper()
+	select {
+	case request := <-requests:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook got no request")
+		return webhookRequest{}
+	}
+}
+
+// testEvent is an event of the rule "cpu.total > 90%", firing or resolved.
+func testEvent(url string, at time.Time, resolved bool) AlertEvent {
+	return AlertEvent{
+		Time: at, Name: "CPU busy", Rule: "cpu.total > 90%", Panel: "CPU", Value: 97.5, Resolved: resolved,
+		rule: &AlertRule{Name: "CPU busy", Rule: "cpu.total > 90%", Webhook: url},
+	}
+}
+
+func testNotifierSettings() AlertActionSettings {
+	settings := defaultAlertActionSettings
+	settings.RetryDelay = 10 * time.Millisecond
+	return settings
+}
+
+func TestNotifierWebhookBody(t *testing.T) {
+	url, requests := startWebhook(t)
+	notifier := newAlertNotifier(testNotifierSettings(), "buildbox")
+	notifier.Notify(testEvent(url, fakeTime, false))
+
+	request := nextRequest(t, requests)
+	if request.contentType != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", request.contentType)
+	}
+	want := alertPayload{
+		Name: "CPU busy", Rule: "cpu.total > 90%", State: "firing", Value: 97.5, Panel: "CPU",
+		Host: "buildbox", Time: "2025-03-14T09:26:53Z",
+	}
+	if request.payload != want {
+		t.Errorf("got  %+v\nwant %+v", request.payload, want)
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	url, requests := startWebhook(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	notifier := newAlertNotifier(testNotifierSettings(), "buildbox")
+	notifier.Notify(testEvent(url, fakeTime, false))
+
+	first, second, third := nextRequest(t, requests), nextRequest(t, requests), nextRequest(t, requests)
+	// The delay doubles after each failed attempt.
+	if delay := second.received.Sub(first.received); delay < 10*time.Millisecond {
+		t.Errorf("first retry after %v, want at least 10ms", delay)
+	}
+	if delay := third.received.Sub(second.received); delay < 20*time.Millisecond {
+		t.Errorf("second retry after %v, want at least 20ms", delay)
+	}
+	if third.payload != first.payload {
+		t.Errorf("retry sent %+v, want %+v", third.payload, first.payload)
+	}
+	select {
+	case failure := <-notifier.Failures:
+		t.Errorf("got failure %q after a successful retry", failure)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierGivesUp(t *testing.T) {
+	url, requests := startWebhook(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	settings := testNotifierSettings()
+	settings.Retries = 1
+	notifier := newAlertNotifier(settings, "buildbox")
+	notifier.Notify(testEvent(url, fakeTime, false))
+
+	nextRequest(t, requests)
+	nextRequest(t, requests)
+	select {
+	case failure := <-notifier.Failures:
+		if !strings.Contains(failure, "webhook for CPU busy") || !strings.Contains(failure, "502 Bad Gateway") {
+			t.Errorf("got failure %q", failure)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failure reported")
+	}
+	select {
+	case request := <-requests:
+		t.Errorf("got %+v after the last retry", request.payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierMinInterval(t *testing.T) {
+	url, requests := startWebhook(t)
+	notifier := newAlertNotifier(testNotifierSettings(), "buildbox")
+
+	notifier.Notify(testEvent(url, fakeTime, false))
+	if got := nextRequest(t, requests).payload; got.Suppressed != 0 {
+		t.Errorf("first notification: %d suppressed, want 0", got.Suppressed)
+	}
+	// Within MinInterval (5 minutes) of the first one: not sent, but counted
+	// in the next notification.
+	notifier.Notify(testEvent(url, fakeTime.Add(time.Minute), false))
+	notifier.Notify(testEvent(url, fakeTime.Add(4*time.Minute), false))
+	notifier.Notify(testEvent(url, fakeTime.Add(6*time.Minute), false))
+	got := nextRequest(t, requests).payload
+	if got.Time != "2025-03-14T09:32:53Z" || got.Suppressed != 2 {
+		t.Errorf("got the notification of %s with %d suppressed, want 09:32:53 with 2", got.Time, got.Suppressed)
+	}
+	notifier.Notify(testEvent(url, fakeTime.Add(12*time.Minute), false))
+	if got := nextRequest(t, requests).payload; got.Suppressed != 0 {
+		t.Errorf("notification after a sent one: %d suppressed, want 0", got.Suppressed)
+	}
+}
+
+func TestNotifierResolve(t *testing.T) {
+	url, requests := startWebhook(t)
+	notifier := newAlertNotifier(testNotifierSettings(), "buildbox")
+
+	// Nothing was sent for the alert firing, so its resolve isn't either.
+	notifier.Notify(testEvent(url, fakeTime, true))
+	notifier.Notify(testEvent(url, fakeTime.Add(time.Second), false))
+	if got := nextRequest(t, requests).payload; got.State != "firing" {
+		t.Errorf("state %q, want firing", got.State)
+	}
+	notifier.Notify(testEvent(url, fakeTime.Add(time.Minute), true))
+	if got := nextRequest(t, requests).payload; got.State != "resolved" || got.Time != "2025-03-14T09:27:53Z" {
+		t.Errorf("got %s at %s, want resolved at 09:27:53", got.State, got.Time)
+	}
+	// Resolved once only.
+	notifier.Notify(testEvent(url, fakeTime.Add(2*time.Minute), true))
+	select {
+	case request := <-requests:
+		t.Errorf("got %+v for an alert already resolved", request.payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierResolveDisabled(t *testing.T) {
+	url, requests := startWebhook(t)
+	settings := testNotifierSettings()
+	settings.NotifyResolve = false
+	notifier := newAlertNotifier(settings, "buildbox")
+
+	notifier.Notify(testEvent(url, fakeTime, false))
+	nextRequest(t, requests)
+	notifier.Notify(testEvent(url, fakeTime.Add(time.Minute), true))
+	select {
+	case request := <-requests:
+		t.Errorf("got %+v with NotifyResolve off", request.payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const maxAlertHistory = 500
+
+// AlertRule is one [[Alerts]] entry of the config file, e.g.
+//
+//	[[Alerts]]
+//	Name = "CPU on fire"
+//	Rule = "cpu.total > 90% for 30s"
+//	Bell = true
+//	Command = "notify-send \"$TERMIDASH_ALERT_NAME\""
+//	Webhook = "http://localhost:8080/alerts"
+//
+// Command and Webhook are run by the alertNotifier.
+type AlertRule struct {
+	Name    string `toml:"Name"`
+	Rule    string `toml:"Rule"`
+	Bell    bool   `toml:"Bell"`
+	Command string `toml:"Command,omitempty"`
+	Webhook string `toml:"Webhook,omitempty"`
+}
+
+// alertCondition is the parsed form of a rule string:
+// "<metric> <op> <threshold>[%] [for <duration>]".
+type alertCondition struct {
+	metric    string
+	op        string
+	threshold float64
+	duration  time.Duration
+}
+
+type AlertEvent struct {
+	Time     time.Time
+	Name     string
+	Rule     string
+	Panel    string
+	Value    float64
+	Resolved bool
+	Bell     bool
+
+	rule *AlertRule
+}
+
+func (event AlertEvent) Message() string {
+	description := event.Name
+	if event.Name != event.Rule {
+		description = fmt.Sprintf("%s (%s)", event.Name, event.Rule)
+	}
+	if event.Resolved {
+		return fmt.Sprintf(tr("Resolved: %s, now %s"), description, formatFloat(event.Value))
+	}
+	return fmt.Sprintf(tr("ALERT: %s, value %s"), description, formatFloat(event.Value))
+}
+
+type alertState struct {
+	rule      AlertRule
+	condition alertCondition
+	since     time.Time
+	firing    bool
+}
+
+// alertEngine evaluates the configured rules against every sample and keeps
+// the history of fired and resolved alerts.
+type alertEngine struct {
+	mu      sync.Mutex
+	states  []*alertState
+	history []AlertEvent
+	errors  []string
+}
+
+func newAlertEngine(rules []AlertRule) *alertEngine {
+	engine := &alertEngine{}
+	for _, rule := range rules {
+		condition, err := parseAlertCondition(rule.Rule)
+		if err != nil {
+			engine.errors = append(engine.errors, fmt.Sprintf("%q: %v", rule.Rule, err))
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Rule
+		}
+		engine.states = append(engine.states, &alertState{rule: rule, condition: condition})
+	}
+	return engine
+}
+
+var alertOperators = []string{">=", "<=", ">", "<"}
+
+func parseAlertCondition(rule string) (alertCondition, error) {
+	var condition alertCondition
+	fields := strings.Fields(rule)
+	opIndex := -1
+	for i, field := range fields {
+		for _, op := range alertOperators {
+			if field == op {
+				opIndex = i
+				condition.op = op
+				break
+			}
+		}
+		if opIndex != -1 {
+			break
+		}
+	}
+	if opIndex < 1 || opIndex+1 >= len(fields) {
+		return condition, fmt.Errorf("expected \"<metric> <op> <value>\" with op one of %s", strings.Join(alertOperators, " "))
+	}
+	condition.metric = strings.Join(fields[:opIndex], " ")
+	if alertPanel(condition.metric) == "" {
+		return condition, fmt.Errorf("unknown metric %q", condition.metric)
+	}
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(fields[opIndex+1], "%"), 64)
+	if err != nil {
+		return condition, fmt.Errorf("invalid threshold %q", fields[opIndex+1])
+	}
+	condition.threshold = threshold
+	rest := fields[opIndex+2:]
+	if len(rest) > 0 {
+		if len(rest) != 2 || rest[0] != "for" {
+			return condition, fmt.Errorf("unexpected %q, expected \"for <duration>\"", strings.Join(rest, " "))
+		}
+		condition.duration, err = time.ParseDuration(rest[1])
+		if err != nil {
+			return condition, fmt.Errorf("invalid duration %q", rest[1])
+		}
+	}
+	return condition, nil
+}
+
+// alertPanel returns which panel a metric belongs to, or "" for an unknown
+// metric. The supported metrics are:
+//
+//	cpu.total, cpu.core<N>       usage in percent
+//	cpu.iowait, cpu.steal, ...   share of the CPU time in percent
+//	load.1, load.5, load.15      load averages
+//	mem.used, swap.used          usage in percent
+//	disk <mountpoint> used       usage in percent
+//	disk <mountpoint> inodes     inode usage in percent
+//	temp.max, temp <sensor key>  temperature in Celsius
+//
+// Inside a cgroup with a CPU quota or a memory limit, cpu.total and mem.used
+// are measured against that limit, like in the panels.
+func alertPanel(metric string) string {
+	fields := strings.Fields(metric)
+	switch {
+	case metric == "cpu.total" || strings.HasPrefix(metric, "cpu.core"):
+		return "cpu"
+	case metric == "load.1" || metric == "load.5" || metric == "load.15":
+		return "cpu"
+	case strings.HasPrefix(metric, "cpu."):
+		if _, ok := cpuTimesMetric(CPUTimesSample{}, metric); ok {
+			return "cpu"
+		}
+	case metric == "mem.used" || metric == "swap.used":
+		return "memory"
+	case len(fields) == 3 && fields[0] == "disk" && (fields[2] == "used" || fields[2] == "inodes"):
+		return "disk"
+	case metric == "temp.max" || (len(fields) == 2 && fields[0] == "temp"):
+		return "temp"
+	}
+	return ""
+}
+
+// sampleMetric looks a metric up in a sample. The second return value is false
+// when the sample doesn't contain it, e.g. an unmounted disk.
+func sampleMetric(sample *Sample, metric string) (float64, bool) {
+	fields := strings.Fields(metric)
+	switch {
+	case metric == "cpu.total":
+		// The share of the cgroup quota doesn't come from the cpu probe.
+		percent, limited := effectiveCPU(sample)
+		return percent, limited || sample.Unavailable["cpu"] == ""
+	case strings.HasPrefix(metric, "cpu.") && sample.Unavailable["cpu"] != "":
+		return 0, false
+	case strings.HasPrefix(metric, "cpu.core"):
+		core, err := strconv.Atoi(strings.TrimPrefix(metric, "cpu.core"))
+		if err != nil || core < 0 || core >= len(sample.CPUPerCore) {
+			return 0, false
+		}
+		return sample.CPUPerCore[core], true
+	case strings.HasPrefix(metric, "load.") && sample.Unavailable["load"] != "":
+		return 0, false
+	case metric == "load.1":
+		return sample.Load1, true
+	case metric == "load.5":
+		return sample.Load5, true
+	case metric == "load.15":
+		return sample.Load15, true
+	case strings.HasPrefix(metric, "cpu."):
+		return cpuTimesMetric(sample.CPUTimes, metric)
+	case metric == "mem.used":
+		_, _, percent, limited := effectiveMemory(sample)
+		return percent, limited || sample.Unavailable["memory"] == ""
+	case metric == "swap.used":
+		if sample.SwapTotal == 0 {
+			return 0, false
+		}
+		return sample.SwapUsedPercent, true
+	case len(fields) == 3 && fields[0] == "disk":
+		for _, disk := range sample.Disks {
+			if disk.Mountpoint != fields[1] {
+				continue
+			}
+			if fields[2] == "inodes" {
+				return disk.InodesUsedPercent, disk.InodesTotal > 0
+			}
+			return disk.UsedPercent, true
+		}
+	case metric == "temp.max":
+		if len(sample.Temps) == 0 {
+			return 0, false
+		}
+		max := sample.Temps[0].Temperature
+		for _, temp := range sample.Temps[1:] {
+			if temp.Temperature > max {
+				max = temp.Temperature
+			}
+		}
+		return max, true
+	case len(fields) == 2 && fields[0] == "temp":
+		for _, temp := range sample.Temps {
+			if temp.SensorKey == fields[1] {
+				return temp.Temperature, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func cpuTimesMetric(times CPUTimesSample, metric string) (float64, bool) {
+	switch metric {
+	case "cpu.user":
+		return times.User, true
+	case "cpu.system":
+		return times.System, true
+	case "cpu.iowait":
+		return times.Iowait, true
+	case "cpu.irq":
+		return times.Irq, true
+	case "cpu.softirq":
+		return times.Softirq, true
+	case "cpu.steal":
+		return times.Steal, true
+	case "cpu.nice":
+		return times.Nice, true
+	}
+	return 0, false
+}
+
+func (condition alertCondition) holds(value float64) bool {
+	switch condition.op {
+	case ">":
+		return value > condition.threshold
+	case ">=":
+		return value >= condition.threshold
+	case "<":
+		return value < condition.threshold
+	case "<=":
+		return value <= condition.threshold
+	}
+	return false
+}
+
+// Evaluate checks every rule against the sample and returns the alerts that
+// started firing or got resolved with it.
+func (engine *alertEngine) Evaluate(sample *Sample) []AlertEvent {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	var events []AlertEvent
+	for _, state := range engine.states {
+		value, ok := sampleMetric(sample, state.condition.metric)
+		holds := ok && state.condition.holds(value)
+		if !holds {
+			state.since = time.Time{}
+			if state.firing {
+				state.firing = false
+				events = append(events, state.event(sample.Time, value, true))
+			}
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = sample.Time
+		}
+		if !state.firing && sample.Time.Sub(state.since) >= state.condition.duration {
+			state.firing = true
+			events = append(events, state.event(sample.Time, value, false))
+		}
+	}
+	engine.history = append(engine.history, events...)
+	if len(engine.history) > maxAlertHistory {
+		engine.history = engine.history[len(engine.history)-maxAlertHistory:]
+	}
+	return events
+}
+
+func (state *alertState) event(at time.Time, value float64, resolved bool) AlertEvent {
+	return AlertEvent{
+		Time:     at,
+		Name:     state.rule.Name,
+		Rule:     state.rule.Rule,
+		Panel:    alertPanel(state.condition.metric),
+		Value:    value,
+		Resolved: resolved,
+		Bell:     state.rule.Bell && !resolved,
+		rule:     &state.rule,
+	}
+}
+
+// FiringPanels returns the set of panels that currently have a firing alert.
+func (engine *alertEngine) FiringPanels() map[string]bool {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	panels := make(map[string]bool)
+	for _, state := range engine.states {
+		if state.firing {
+			panels[alertPanel(state.condition.metric)] = true
+		}
+	}
+	return panels
+}
+
+// ActiveCount returns how many rules are currently firing.
+func (engine *alertEngine) ActiveCount() int {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	count := 0
+	for _, state := range engine.states {
+		if state.firing {
+			count++
+		}
+	}
+	return count
+}
+
+func renderAlertHistory(theme *Theme, engine *alertEngine) string {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	var text string
+	for _, err := range engine.errors {
+		text += fmt.Sprintf("[%s]"+tr("Invalid rule %s")+"[-]\n", theme.BarRed.TrueColor().String(), tview.Escape(err))
+	}
+	if len(engine.states) == 0 {
+		text += tr("No alert rules configured. Add [[Alerts]] entries to the config file.") + "\n"
+	}
+	if len(engine.history) == 0 {
+		return text + tr("No alert fired yet.")
+	}
+	for i := len(engine.history) - 1; i >= 0; i-- {
+		event := engine.history[i]
+		colorCode := fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
+		if event.Resolved {
+			colorCode = fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
+		}
+		text += fmt.Sprintf("%s %s%s[-]\n", formatTime(event.Time, true), colorCode, tview.Escape(event.Message()))
+	}
+	return text
+}
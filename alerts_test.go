@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestSampleMetricUnavailable(t *testing.T) {
+	failed := fakeSample(0)
+	failed.Unavailable = probeErrors{"cpu": "permission denied", "memory": "permission denied", "load": "not implemented yet"}
+	failed.CPUTotal, failed.MemTotal, failed.MemUsed, failed.MemUsedPercent = 0, 0, 0, 0
+	limited := fakeSample(0)
+	limited.Unavailable = failed.Unavailable
+	limited.Cgroup = &CgroupSample{CPUQuota: 2, CPUPercent: 40, MemoryMax: 4 << 30, MemoryCurrent: 1 << 30}
+
+	for _, test := range []struct {
+		name   string
+		sample *Sample
+		metric string
+		value  float64
+		ok     bool
+	}{
+		{"cpu", fakeSample(0), "cpu.total", 35, true},
+		{"cpu failed", failed, "cpu.total", 0, false},
+		{"cpu times failed", failed, "cpu.user", 0, false},
+		{"cpu quota", limited, "cpu.total", 40, true},
+		{"memory", fakeSample(0), "mem.used", 56.25, true},
+		{"memory failed", failed, "mem.used", 0, false},
+		{"memory limit", limited, "mem.used", 25, true},
+		{"load", fakeSample(0), "load.1", 2.5, true},
+		{"load failed", failed, "load.1", 0, false},
+	} {
+		value, ok := sampleMetric(test.sample, test.metric)
+		if value != test.value || ok != test.ok {
+			t.Errorf("%s: %s = %v, %v, want %v, %v", test.name, test.metric, value, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestAlertHistoryEscaped(t *testing.T) {
+	engine := newAlertEngine([]AlertRule{{Name: "[red]hot[-]", Rule: "cpu.total > 10%"}, {Rule: "cpu.total > [90]%"}})
+	engine.Evaluate(fakeSample(0))
+	text := renderAlertHistory(&defaultTheme, engine)
+	// Once the tags are drawn, the names and rules are shown as written.
+	shown := tview.NewTextView().SetDynamicColors(true).SetText(text).GetText(true)
+	for _, want := range []string{"ALERT: [red]hot[-] (cpu.total > 10%)", `Invalid rule "cpu.total > [90]%"`} {
+		if !strings.Contains(shown, want) {
+			t.Errorf("%q not in the history:\n%s", want, text)
+		}
+	}
+}
+
+func TestParseAlertCondition(t *testing.T) {
+	for _, test := range []struct {
+		rule string
+		want alertCondition
+		err  string
+	}{
+		{"cpu.total > 90%", alertCondition{"cpu.total", ">", 90, 0}, ""},
+		{"mem.used >= 80 for 30s", alertCondition{"mem.used", ">=", 80, 30 * time.Second}, ""},
+		{"disk / used > 95% for 1m30s", alertCondition{"disk / used", ">", 95, 90 * time.Second}, ""},
+		{"temp coretemp_core_0 < 10.5", alertCondition{"temp coretemp_core_0", "<", 10.5, 0}, ""},
+		{"load.1 <= 0.5", alertCondition{"load.1", "<=", 0.5, 0}, ""},
+		{"cpu.total => 90", alertCondition{}, "expected"},
+		{"cpu.total == 90", alertCondition{}, "expected"},
+		{"cpu.total>90", alertCondition{}, "expected"},
+		{"> 90", alertCondition{}, "expected"},
+		{"cpu.total >", alertCondition{}, "expected"},
+		{"", alertCondition{}, "expected"},
+		{"gpu.total > 90", alertCondition{}, `unknown metric "gpu.total"`},
+		{"cpu.bogus > 90", alertCondition{}, `unknown metric "cpu.bogus"`},
+		{"cpu.total > high", alertCondition{}, `invalid threshold "high"`},
+		{"cpu.total > 90%%", alertCondition{}, `invalid threshold "90%%"`},
+		{"cpu.total > 90 during 30s", alertCondition{}, `unexpected "during 30s"`},
+		{"cpu.total > 90 for", alertCondition{}, `unexpected "for"`},
+		{"cpu.total > 90 for 30", alertCondition{}, `invalid duration "30"`},
+		{"cpu.total > 90 for soon", alertCondition{}, `invalid duration "soon"`},
+	} {
+		condition, err := parseAlertCondition(test.rule)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: %v", test.rule, err)
+		case test.err == "" && condition != test.want:
+			t.Errorf("%q: got %+v, want %+v", test.rule, condition, test.want)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%q: got the error %v, want %q", test.rule, err, test.err)
+		}
+	}
+}
+
+func TestAlertEvaluateFor(t *testing.T) {
+	engine := newAlertEngine([]AlertRule{{Name: "CPU busy", Rule: "cpu.total > 90% for 30s"}})
+	// The CPU usage of the samples, every 10 seconds.
+	usage := []float64{95, 95, 95, 80, 95, 95, 95, 95, 50, 95, 95, 95, 95}
+	var got []string
+	for i, percent := range usage {
+		sample := fakeSample(0)
+		sample.Time = fakeTime.Add(time.Duration(i) * 10 * time.Second)
+		sample.CPUTotal = percent
+		for _, event := range engine.Evaluate(sample) {
+			state := "fired"
+			if event.Resolved {
+				state = "resolved"
+			}
+			got = append(got, fmt.Sprintf("%s at %d: %v", state, i, event.Value))
+		}
+		if firing := engine.ActiveCount() == 1; firing != engine.FiringPanels()["cpu"] {
+			t.Errorf("sample %d: the active count and the firing panels disagree", i)
+		}
+	}
+	// Above the threshold for 30s: not at 2 (20s), reset by 3, fires at 7,
+	// then again 30s after 9.
+	want := []string{"fired at 7: 95", "resolved at 8: 50", "fired at 12: 95"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if history := len(engine.history); history != len(want) {
+		t.Errorf("%d events in the history, want %d", history, len(want))
+	}
+}
+
+func TestAlertEvaluateMissingMetric(t *testing.T) {
+	engine := newAlertEngine([]AlertRule{{Rule: "disk /data used > 90%"}})
+	sample := fakeSample(0)
+	sample.Disks = append(sample.Disks, DiskSample{Mountpoint: "/data", UsedPercent: 97})
+	if events := engine.Evaluate(sample); len(events) != 1 || events[0].Resolved || events[0].Name != "disk /data used > 90%" || events[0].Panel != "disk" {
+		t.Errorf("got %+v, want the alert fired, named after its rule", events)
+	}
+	// Unmounted: the alert is resolved.
+	if events := engine.Evaluate(fakeSample(1)); len(events) != 1 || !events[0].Resolved {
+		t.Errorf("got %+v once the disk is gone, want the alert resolved", events)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// barWidth is the width of the bars drawn outside of a panel (e.g. by
+// --fetch), or in a panel not drawn yet.
+const barWidth = 20
+
+// minBarWidth is the narrowest a bar gets in a small panel.
+const minBarWidth = 10
+
+// partialBlocks are the glyphs of the last, partly filled cell of a "blocks"
+// bar, from 1/8 to 7/8 of a cell.
+var partialBlocks = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// drawnWidths is the inner width of each panel at its last draw. The panels
+// are drawn on the application's goroutine but their text is built on the
+// collection one.
+var drawnWidths sync.Map
+
+// trackWidth remembers the width of the panel each time it is drawn.
+func trackWidth(panel *tview.TextView) {
+	panel.SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+		drawnWidths.Store(panel, width-2)
+		return x + 1, y + 1, width - 2, height - 2
+	})
+}
+
+// panelWidth is the inner width of the panel at its last draw, 0 if it
+// wasn't drawn yet.
+func panelWidth(panel *tview.TextView) int {
+	width, ok := drawnWidths.Load(panel)
+	if !ok {
+		return 0
+	}
+	return width.(int)
+}
+
+// fitBarWidth is the width of the bars on a line of width columns where the
+// reserved ones are taken by the text around the bars. BarWidth, when set,
+// replaces it.
+func fitBarWidth(width, reserved int) int {
+	if userPrefs.BarWidth > 0 {
+		return userPrefs.BarWidth
+	}
+	if width <= 0 {
+		return barWidth
+	}
+	return max(width-reserved, minBarWidth)
+}
+
+// fitBars renders the text of a panel with the widest bars that fit on its
+// lines, see fitBarWidth. The text around the bars is measured by rendering
+// the panel twice, with bars one cell apart: a line with n bars gets n cells
+// wider, the other lines don't change.
+func fitBars(panel *tview.TextView, render func(barWidth int) string) string {
+	width := panelWidth(panel)
+	if userPrefs.BarWidth > 0 || width <= 0 {
+		return render(fitBarWidth(width, 0))
+	}
+	narrow := strings.Split(render(minBarWidth), "\n")
+	wide := strings.Split(render(minBarWidth+1), "\n")
+	fit := math.MaxInt
+	for i := range min(len(narrow), len(wide)) {
+		narrowWidth := tview.TaggedStringWidth(narrow[i])
+		if bars := tview.TaggedStringWidth(wide[i]) - narrowWidth; bars > 0 {
+			reserved := narrowWidth - bars*minBarWidth
+			fit = min(fit, (width-reserved)/bars)
+		}
+	}
+	if fit == math.MaxInt {
+		return strings.Join(narrow, "\n")
+	}
+	return render(max(fit, minBarWidth))
+}
+
+// barColor is the color tag of a value shown next to a bar: green, then
+// yellow from 50% and red from 80%.
+func barColor(theme *Theme, percent float64) string {
+	if percent >= 80 {
+		return fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
+	} else if percent >= 50 {
+		return fmt.Sprintf("[%s]", theme.BarYellow.TrueColor().String())
+	}
+	return fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
+}
+
+// createBar draws a bar of width cells, and returns it with the color of the
+// value (see barColor). With BarStyle = "blocks", the bar is drawn with full
+// blocks and a partial block for the last cell. With BarGradient, each cell
+// gets the color of its position in the bar instead of the value's.
+func createBar(theme *Theme, percent float64, filledChar, emptyChar string, width int) (string, string) {
+	percent = math.Max(0, math.Min(100, percent))
+	colorCode := barColor(theme, percent)
+	cells := percent / 100.0 * float64(width)
+	filledBlocks := int(cells)
+	var partial string
+	if userPrefs.BarStyle == "blocks" {
+		filledChar = "█"
+		if eighths := int((cells - float64(filledBlocks)) * 8); eighths > 0 && filledBlocks < width {
+			partial = partialBlocks[eighths-1]
+		}
+	}
+	emptyBlocks := width - filledBlocks
+	if partial != "" {
+		emptyBlocks--
+	}
+	if !userPrefs.BarGradient {
+		filledString := strings.Repeat(filledChar, filledBlocks) + partial
+		emptyString := strings.Repeat(emptyChar, emptyBlocks)
+		return colorCode + "[" + filledString + emptyString + "]" + "[-]", colorCode
+	}
+	bar := "[-]["
+	for i := 0; i < filledBlocks; i++ {
+		bar += gradientColor(theme, i, width) + filledChar
+	}
+	if partial != "" {
+		bar += gradientColor(theme, filledBlocks, width) + partial
+	}
+	return bar + "[-]" + strings.Repeat(emptyChar, emptyBlocks) + "]", colorCode
+}
+
+// gradientColor is the color tag of the i-th cell of a gradient bar, going
+// from the theme's green to its yellow in the middle and its red at the end.
+func gradientColor(theme *Theme, i, width int) string {
+	position := (float64(i) + 0.5) / float64(width)
+	from, to := theme.BarGreen, theme.BarYellow
+	if position > 0.5 {
+		from, to = theme.BarYellow, theme.BarRed
+		position -= 0.5
+	}
+	position *= 2
+	r1, g1, b1 := from.RGB()
+	r2, g2, b2 := to.RGB()
+	mix := func(a, b int32) int32 {
+		return a + int32(math.Round(float64(b-a)*position))
+	}
+	return fmt.Sprintf("[#%02x%02x%02x]", mix(r1, r2), mix(g1, g2), mix(b1, b2))
+}
+
+// stackSegment is one part of a stacked bar, in percent of the whole bar.
+type stackSegment struct {
+	Label   string
+	Percent float64
+	Value   string
+}
+
+// createStackedBar draws the segments one after the other, each in its own
+// color from the theme's StackColors, and returns the bar and a legend line.
+// Whatever the segments don't cover is drawn with emptyChar.
+func createStackedBar(theme *Theme, segments []stackSegment, filledChar, emptyChar string, width int) (string, string) {
+	if userPrefs.BarStyle == "blocks" {
+		filledChar = "█"
+	}
+	var bar, legend string
+	usedBlocks := 0
+	for i, segment := range segments {
+		colorCode := fmt.Sprintf("[%s]", theme.StackColors[i%len(theme.StackColors)].TrueColor().String())
+		blocks := int(math.Round((segment.Percent / 100.0) * float64(width)))
+		if usedBlocks+blocks > width {
+			blocks = width - usedBlocks
+		}
+		if blocks > 0 {
+			bar += colorCode + strings.Repeat(filledChar, blocks)
+			usedBlocks += blocks
+		}
+		legend += fmt.Sprintf("%s■[-] %s %s ", colorCode, segment.Label, segment.Value)
+	}
+	return "[-][" + bar + "[-]" + strings.Repeat(emptyChar, width-usedBlocks) + "]", strings.TrimSpace(legend)
+}
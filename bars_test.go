@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestFitBars(t *testing.T) {
+	useDefaultPreferences(t)
+	bar := func(width int) string {
+		bar, _ := createBar(&defaultTheme, 50, "|", "-", width)
+		return bar
+	}
+	for _, test := range []struct {
+		name   string
+		width  int
+		render func(width int) string
+		want   int
+	}{
+		{"one bar", 40, func(width int) string { return "title\nCPU0 " + bar(width) + " 12%" }, 40 - 9 - 2},
+		// The widest text around a bar decides.
+		{"longest label", 60, func(width int) string {
+			return "Swap " + bar(width) + " 75.00% (3.00 GiB/4.00 GiB)\nCPU " + bar(width)
+		}, 60 - 32 - 2},
+		{"two bars", 40, func(width int) string { return bar(width) + " " + bar(width) }, (40 - 5) / 2},
+		{"too narrow", 20, func(width int) string { return "a long label of the bar " + bar(width) }, minBarWidth},
+		{"not drawn", 0, func(width int) string { return "CPU0 " + bar(width) }, barWidth},
+	} {
+		panel := tview.NewTextView()
+		if test.width > 0 {
+			drawnWidths.Store(panel, test.width)
+		}
+		got := 0
+		text := fitBars(panel, func(width int) string {
+			got = width
+			return test.render(width)
+		})
+		if got != test.want {
+			t.Errorf("%s: bars of %d cells, want %d", test.name, got, test.want)
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if width := tview.TaggedStringWidth(line); test.width > 0 && test.want > minBarWidth && width > test.width {
+				t.Errorf("%s: the line %q is %d cells wide, more than the panel", test.name, line, width)
+			}
+		}
+	}
+
+	panel := tview.NewTextView()
+	drawnWidths.Store(panel, 40)
+	if text := fitBars(panel, func(width int) string { return "no bar here" }); text != "no bar here" {
+		t.Errorf("without bars, got %q", text)
+	}
+	userPrefs.BarWidth = 7
+	if text := fitBars(panel, func(width int) string { return fmt.Sprint(width) }); text != "7" {
+		t.Errorf("with BarWidth = 7, got bars of %s cells", text)
+	}
+}
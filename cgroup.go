@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupSample is what the cgroup TermiDash runs in (a container, a systemd
+// slice...) allows it to use. A zero limit means there is none.
+type CgroupSample struct {
+	Version int
+	Path    string
+
+	CPUQuota   float64 // in CPUs, e.g. 1.5
+	CPUUsage   uint64  // total CPU time used, in microseconds
+	CPUPercent float64 // of the quota, since the previous tick
+
+	MemoryMax     uint64
+	MemoryCurrent uint64
+
+	PidsMax     uint64
+	PidsCurrent uint64
+}
+
+// noCgroupLimit is the value cgroup v1 reports for an unlimited memory, which
+// is the largest page-aligned int64 rather than "max".
+const noCgroupLimit = 1 << 62
+
+// readCgroup finds the cgroup of the current process and reads its limits.
+// root is the directory holding "proc" and "sys", "/" on a real system, so
+// that a fixture tree can be read instead. It returns nil when there are no
+// cgroups, e.g. on anything but Linux.
+func readCgroup(root string) *CgroupSample {
+	paths, err := cgroupPaths(filepath.Join(root, "proc", "self", "cgroup"))
+	if err != nil {
+		return nil
+	}
+	mount := filepath.Join(root, "sys", "fs", "cgroup")
+	if _, err := os.Stat(filepath.Join(mount, "cgroup.controllers")); err == nil {
+		return readCgroupV2(mount, paths[""])
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return readCgroupV1(mount, paths)
+}
+
+// cgroupPaths parses /proc/self/cgroup, whose lines are
+// "hierarchy-ID:controllers:path", into the path of each controller. The
+// cgroup v2 hierarchy has no controllers and is stored under "".
+func cgroupPaths(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			paths[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			paths[controller] = fields[2]
+		}
+	}
+	return paths, scanner.Err()
+}
+
+func readCgroupV2(mount, path string) *CgroupSample {
+	cg := &CgroupSample{Version: 2, Path: path}
+	dirs := cgroupDirs(mount, path)
+	for _, dir := range dirs {
+		content, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) != 2 || fields[0] == "max" {
+			continue
+		}
+		quota, errQuota := strconv.ParseFloat(fields[0], 64)
+		period, errPeriod := strconv.ParseFloat(fields[1], 64)
+		if errQuota == nil && errPeriod == nil && period > 0 {
+			cg.CPUQuota = lowestQuota(cg.CPUQuota, quota/period)
+		}
+	}
+	cg.CPUUsage = readCgroupKey(filepath.Join(dirs[0], "cpu.stat"), "usage_usec")
+	cg.MemoryMax = lowestCgroupLimit(dirs, "memory.max")
+	cg.MemoryCurrent, _ = readCgroupValue(filepath.Join(dirs[0], "memory.current"))
+	cg.PidsMax = lowestCgroupLimit(dirs, "pids.max")
+	cg.PidsCurrent, _ = readCgroupValue(filepath.Join(dirs[0], "pids.current"))
+	return cg
+}
+
+func readCgroupV1(mount string, paths map[string]string) *CgroupSample {
+	cg := &CgroupSample{Version: 1, Path: paths["memory"]}
+	if path, ok := paths["cpu"]; ok {
+		dirs := cgroupDirs(filepath.Join(mount, "cpu"), path)
+		for _, dir := range dirs {
+			quota, okQuota := readCgroupInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+			period, okPeriod := readCgroupInt(filepath.Join(dir, "cpu.cfs_period_us"))
+			if okQuota && okPeriod && quota > 0 && period > 0 {
+				cg.CPUQuota = lowestQuota(cg.CPUQuota, float64(quota)/float64(period))
+			}
+		}
+	}
+	if path, ok := paths["cpuacct"]; ok {
+		dirs := cgroupDirs(filepath.Join(mount, "cpuacct"), path)
+		if usage, ok := readCgroupValue(filepath.Join(dirs[0], "cpuacct.usage")); ok {
+			cg.CPUUsage = usage / 1000
+		}
+	}
+	if path, ok := paths["memory"]; ok {
+		dirs := cgroupDirs(filepath.Join(mount, "memory"), path)
+		cg.MemoryMax = lowestCgroupLimit(dirs, "memory.limit_in_bytes")
+		cg.MemoryCurrent, _ = readCgroupValue(filepath.Join(dirs[0], "memory.usage_in_bytes"))
+	}
+	if path, ok := paths["pids"]; ok {
+		dirs := cgroupDirs(filepath.Join(mount, "pids"), path)
+		cg.PidsMax = lowestCgroupLimit(dirs, "pids.max")
+		cg.PidsCurrent, _ = readCgroupValue(filepath.Join(dirs[0], "pids.current"))
+	}
+	return cg
+}
+
+// cgroupDirs lists the directory of a cgroup and the ones of its parents up
+// to the mount point, since a limit set on a parent applies to its children
+// too. In a container with its own cgroup namespace the path may not exist
+// under the mount point: the mount point itself is the container's cgroup.
+func cgroupDirs(mount, path string) []string {
+	dir := filepath.Join(mount, path)
+	if _, err := os.Stat(dir); err != nil {
+		return []string{mount}
+	}
+	var dirs []string
+	for strings.HasPrefix(dir, mount) {
+		dirs = append(dirs, dir)
+		if dir == mount {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return dirs
+}
+
+// readCgroupValue reads a file holding a single number. "max", or the huge
+// number cgroup v1 uses instead, means there is no limit and gives false.
+func readCgroupValue(file string) (uint64, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil || value >= noCgroupLimit {
+		return 0, false
+	}
+	return value, true
+}
+
+// readCgroupInt is readCgroupValue for the files that use -1 as "no limit".
+func readCgroupInt(file string) (int64, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// readCgroupKey reads one "key value" line of a file like cpu.stat.
+func readCgroupKey(file, key string) uint64 {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			value, _ := strconv.ParseUint(fields[1], 10, 64)
+			return value
+		}
+	}
+	return 0
+}
+
+// lowestCgroupLimit is the tightest limit set in any of the directories, or
+// 0 when none of them has one.
+func lowestCgroupLimit(dirs []string, name string) uint64 {
+	var lowest uint64
+	for _, dir := range dirs {
+		value, ok := readCgroupValue(filepath.Join(dir, name))
+		if ok && (lowest == 0 || value < lowest) {
+			lowest = value
+		}
+	}
+	return lowest
+}
+
+func lowestQuota(current, quota float64) float64 {
+	if current == 0 || quota < current {
+		return quota
+	}
+	return current
+}
+
+// effectiveCPU is the CPU usage shown in the panels and used by the alerts:
+// the share of the cgroup's CPU quota when there is one, the host-wide usage
+// otherwise.
+func effectiveCPU(sample *Sample) (float64, bool) {
+	if sample.Cgroup != nil && sample.Cgroup.CPUQuota > 0 {
+		return sample.Cgroup.CPUPercent, true
+	}
+	return sample.CPUTotal, false
+}
+
+// effectiveMemory is the memory total, used and percent shown in the panels
+// and used by the alerts: against the cgroup's memory limit when there is one
+// lower than the host's memory, against the host's memory otherwise.
+func effectiveMemory(sample *Sample) (uint64, uint64, float64, bool) {
+	cg := sample.Cgroup
+	if cg != nil && cg.MemoryMax > 0 && (sample.MemTotal == 0 || cg.MemoryMax < sample.MemTotal) {
+		return cg.MemoryMax, cg.MemoryCurrent, float64(cg.MemoryCurrent) / float64(cg.MemoryMax) * 100, true
+	}
+	return sample.MemTotal, sample.MemUsed, sample.MemUsedPercent, false
+}
+
+// renderCgroup describes the cgroup and its limits for the Hardware tab.
+func renderCgroup(cg *CgroupSample) string {
+	if cg == nil {
+		return tr("No cgroup found.") + "\n"
+	}
+	text := fmt.Sprintf(tr("Version: %d")+"\n"+tr("Path: %s")+"\n", cg.Version, cg.Path)
+	if cg.CPUQuota > 0 {
+		text += fmt.Sprintf(tr("CPU quota: %s CPUs")+"\n", formatFloat(cg.CPUQuota))
+	} else {
+		text += tr("CPU quota: none") + "\n"
+	}
+	if cg.MemoryMax > 0 {
+		text += fmt.Sprintf(tr("Memory: %s / %s")+"\n", formatBytes(cg.MemoryCurrent), formatBytes(cg.MemoryMax))
+	} else {
+		text += fmt.Sprintf(tr("Memory: %s / no limit")+"\n", formatBytes(cg.MemoryCurrent))
+	}
+	if cg.PidsMax > 0 {
+		text += fmt.Sprintf(tr("Pids: %d / %d")+"\n", cg.PidsCurrent, cg.PidsMax)
+	} else {
+		text += fmt.Sprintf(tr("Pids: %d / no limit")+"\n", cg.PidsCurrent)
+	}
+	return text
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const mib = 1 << 20
+
+func TestReadCgroup(t *testing.T) {
+	for _, test := range []struct {
+		root string
+		want *CgroupSample
+	}{
+		{"v2", &CgroupSample{
+			Version: 2, Path: "/app.slice/web.service",
+			CPUQuota: 1.5, CPUUsage: 5000000,
+			MemoryMax: 512 * mib, MemoryCurrent: 100 * mib,
+			PidsMax: 100, PidsCurrent: 12,
+		}},
+		// The tightest limit wins, whether it is set on the cgroup or on a
+		// parent.
+		{"v2-nested", &CgroupSample{
+			Version: 2, Path: "/parent/child",
+			CPUQuota:  0.5,
+			MemoryMax: 256 * mib, MemoryCurrent: 200 * mib,
+			PidsMax: 50, PidsCurrent: 3,
+		}},
+		{"v2-no-limit", &CgroupSample{
+			Version: 2, Path: "/user.slice",
+			MemoryCurrent: 70 * mib, PidsCurrent: 40,
+		}},
+		// In a cgroup namespace the path is not under the mount point, which
+		// is the container's cgroup.
+		{"v2-namespace", &CgroupSample{
+			Version: 2, Path: "/docker/4f2a",
+			CPUQuota:  2,
+			MemoryMax: 2048 * mib, MemoryCurrent: 500 * mib,
+		}},
+		{"v1", &CgroupSample{
+			Version: 1, Path: "/docker/4f2a",
+			CPUQuota: 2, CPUUsage: 3000000,
+			MemoryMax: 1024 * mib, MemoryCurrent: 256 * mib,
+			PidsCurrent: 7,
+		}},
+		{"v1-no-limit", &CgroupSample{
+			Version: 1, Path: "/user.slice",
+			MemoryCurrent: 1 * mib,
+		}},
+		{"empty", nil},
+		{"missing", nil},
+	} {
+		t.Run(test.root, func(t *testing.T) {
+			got := readCgroup(filepath.Join("testdata", "cgroup", test.root))
+			if got == nil || test.want == nil {
+				if got != test.want {
+					t.Errorf("got %+v, want %+v", got, test.want)
+				}
+				return
+			}
+			if *got != *test.want {
+				t.Errorf("got  %+v\nwant %+v", *got, *test.want)
+			}
+		})
+	}
+}
+
+func TestCgroupDirs(t *testing.T) {
+	mount := filepath.Join("testdata", "cgroup", "v2-nested", "sys", "fs", "cgroup")
+	for _, test := range []struct {
+		path string
+		want []string
+	}{
+		{"/parent/child", []string{filepath.Join(mount, "parent", "child"), filepath.Join(mount, "parent"), mount}},
+		{"/parent", []string{filepath.Join(mount, "parent"), mount}},
+		{"/", []string{mount}},
+		{"/docker/4f2a", []string{mount}},
+	} {
+		if got := cgroupDirs(mount, test.path); !slices.Equal(got, test.want) {
+			t.Errorf("cgroupDirs(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestEffectiveCPU(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		cgroup *CgroupSample
+		want   float64
+		quota  bool
+	}{
+		{"no cgroup", nil, 40, false},
+		{"no quota", &CgroupSample{CPUPercent: 90}, 40, false},
+		{"quota", &CgroupSample{CPUQuota: 0.5, CPUPercent: 90}, 90, true},
+	} {
+		sample := &Sample{CPUTotal: 40, Cgroup: test.cgroup}
+		got, quota := effectiveCPU(sample)
+		if got != test.want || quota != test.quota {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.name, got, quota, test.want, test.quota)
+		}
+	}
+}
+
+func TestEffectiveMemory(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		memTotal uint64
+		cgroup   *CgroupSample
+		total    uint64
+		used     uint64
+		percent  float64
+		limited  bool
+	}{
+		{"no cgroup", 1024 * mib, nil, 1024 * mib, 256 * mib, 25, false},
+		{"no limit", 1024 * mib, &CgroupSample{MemoryCurrent: 100 * mib}, 1024 * mib, 256 * mib, 25, false},
+		{"limit", 1024 * mib, &CgroupSample{MemoryMax: 512 * mib, MemoryCurrent: 128 * mib}, 512 * mib, 128 * mib, 25, true},
+		{"limit above the host", 1024 * mib, &CgroupSample{MemoryMax: 2048 * mib, MemoryCurrent: 128 * mib}, 1024 * mib, 256 * mib, 25, false},
+		{"host memory unknown", 0, &CgroupSample{MemoryMax: 512 * mib, MemoryCurrent: 256 * mib}, 512 * mib, 256 * mib, 50, true},
+	} {
+		sample := &Sample{MemTotal: test.memTotal, MemUsed: 256 * mib, MemUsedPercent: 25, Cgroup: test.cgroup}
+		total, used, percent, limited := effectiveMemory(sample)
+		if total != test.total || used != test.used || percent != test.percent || limited != test.limited {
+			t.Errorf("%s: got %v, %v, %v, %v, want %v, %v, %v, %v",
+				test.name, total, used, percent, limited, test.total, test.used, test.percent, test.limited)
+		}
+	}
+}
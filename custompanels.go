@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// CustomPanel is one [[Panels]] entry of the config file: a panel showing
+// the output of a shell command, run again every Interval.
+//
+//	[[Panels]]
+//	Title = "Build queue"
+//	Command = "curl -s ci.lan/queue/length"
+//	Interval = "30s"
+//	Timeout = "5s"
+//	Tab = "Custom"
+//	Bar = true
+//	Max = 50
+//	Unit = "jobs"
+//
+// Panels with the same Tab are stacked on that tab, after the built-in ones.
+// With Bar, the first number of the output is drawn as a bar, Max being a
+// full bar.
+type CustomPanel struct {
+	Title    string        `toml:"Title"`
+	Command  string        `toml:"Command"`
+	Interval time.Duration `toml:"Interval,omitempty"`
+	Timeout  time.Duration `toml:"Timeout,omitempty"`
+	Tab      string        `toml:"Tab,omitempty"`
+	Bar      bool          `toml:"Bar,omitempty"`
+	Max      float64       `toml:"Max,omitempty"`
+	Unit     string        `toml:"Unit,omitempty"`
+}
+
+// customPanelNumber is the number drawn by the Bar mode, e.g. "12" in
+// "12 jobs queued".
+var customPanelNumber = regexp.MustCompile(`-?[0-9]+(\.[0-9]+)?`)
+
+// customPanel runs the command of a CustomPanel and shows its output.
+type customPanel struct {
+	settings CustomPanel
+	view     *tview.TextView
+}
+
+func newCustomPanel(settings CustomPanel) *customPanel {
+	if settings.Interval <= 0 {
+		settings.Interval = 5 * time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 5 * time.Second
+	}
+	if settings.Max <= 0 {
+		settings.Max = 100
+	}
+	title := settings.Title
+	if title == "" {
+		title = settings.Command
+	}
+	panel := &customPanel{settings: settings, view: newPanel(title)}
+	panel.view.SetScrollable(true)
+	panel.view.SetText(fmt.Sprintf(tr("Running %s..."), tview.Escape(settings.Command)))
+	return panel
+}
+
+// run runs the command every interval, until the program exits. The
+// command isn't run while the refresh is paused.
+func (panel *customPanel) run(app *tview.Application, refresh *refreshControl) {
+	defer exitOnPanic()
+	for {
+		if _, paused := refresh.State(); !paused {
+			output, err := panel.execute()
+			text := fitBars(panel.view, func(width int) string {
+				return renderCustomPanel(currentTheme, panel.settings, output, err, width)
+			})
+			app.QueueUpdateDraw(func() {
+				panel.view.SetText(text)
+			})
+		}
+		time.Sleep(panel.settings.Interval)
+	}
+}
+
+// execute runs the command, killing it after the timeout. The error carries
+// what the command wrote on its standard error.
+func (panel *customPanel) execute() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), panel.settings.Timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, panel.settings.Command)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf(tr("timed out after %s"), panel.settings.Timeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return string(output), fmt.Errorf("%v: %s", err, message)
+		}
+		return string(output), err
+	}
+	return string(output), nil
+}
+
+// renderCustomPanel builds the text of a custom panel: the bar if asked,
+// the output with its ANSI colors, and the error in red if the command
+// failed.
+func renderCustomPanel(theme *Theme, settings CustomPanel, output string, err error, width int) string {
+	output = strings.TrimRight(output, "\n")
+	var text string
+	if settings.Bar && err == nil {
+		match := customPanelNumber.FindString(output)
+		value, parseErr := strconv.ParseFloat(match, 64)
+		if parseErr != nil {
+			err = errors.New(tr("no number in the output"))
+		} else {
+			percent := math.Max(0, math.Min(100, value/settings.Max*100))
+			bar, colorCode := createBar(theme, percent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+			text += fmt.Sprintf("%s %s%s %s[-]\n", bar, colorCode, formatNumber(value, -1), tview.Escape(settings.Unit))
+		}
+	}
+	if output != "" {
+		text += tview.TranslateANSI(tview.Escape(output)) + "[-:-:-]\n"
+	}
+	if err != nil {
+		text += fmt.Sprintf("[%s]"+tr("Error: %s")+"[-]\n", theme.BarRed.TrueColor().String(), tview.Escape(err.Error()))
+	}
+	return text
+}
+
+// addCustomPanels creates the panels of the config file, and starts running
+// their commands.
+func (d *dashboard) addCustomPanels(settings []CustomPanel) {
+	for _, panelSettings := range settings {
+		tab := panelSettings.Tab
+		if tab == "" {
+			tab = "Custom"
+		}
+		panel := newCustomPanel(panelSettings)
+		d.addToPanelTab(tab, panel.view)
+		d.themed = append(d.themed, themedPanel{panel.view, func(theme *Theme) PanelStyle { return theme.InfoPanel }})
+		go panel.run(d.app, d.refresh)
+	}
+}
+
+// panelTab is a tab made of config-defined panels, stacked in the order of
+// the config file.
+type panelTab struct {
+	name  string
+	views []tview.Primitive
+}
+
+// addToPanelTab puts a config-defined panel on the tab with that name.
+func (d *dashboard) addToPanelTab(name string, view tview.Primitive) {
+	for _, tab := range d.panelTabs {
+		if tab.name == name {
+			tab.views = append(tab.views, view)
+			return
+		}
+	}
+	d.panelTabs = append(d.panelTabs, &panelTab{name: name, views: []tview.Primitive{view}})
+}
+
+// builtinTabs are the tabs of the dashboard itself.
+var builtinTabs = []string{"Overview", "Processes", "Network", "Storage", "Sensors", "Hardware", "Users", "Pressure"}
+
+// checkPanelTabs rejects the config-defined panels and log tails put on a
+// built-in tab, which would replace it.
+func checkPanelTabs(panels []CustomPanel, tails []LogTailSettings) error {
+	isBuiltin := func(tab string) bool {
+		return slices.ContainsFunc(builtinTabs, func(builtin string) bool {
+			return strings.EqualFold(builtin, tab)
+		})
+	}
+	for _, panel := range panels {
+		if isBuiltin(panel.Tab) {
+			return fmt.Errorf(tr("the panel %q can't be on the built-in tab %q, pick another Tab"), panel.Title, panel.Tab)
+		}
+	}
+	for _, tail := range tails {
+		if tail.Slot != "disk" && isBuiltin(tail.Tab) {
+			return fmt.Errorf(tr("the panel %q can't be on the built-in tab %q, pick another Tab"), tail.Title, tail.Tab)
+		}
+	}
+	return nil
+}
+
+// addPanelTabs adds the tabs of the config-defined panels after the built-in
+// ones.
+func (d *dashboard) addPanelTabs() {
+	for _, tab := range d.panelTabs {
+		grid := newTabGrid()
+		grid.SetRows(make([]int, len(tab.views))...)
+		for i, view := range tab.views {
+			grid.AddItem(view, i, 0, 1, 1, 0, 0, i == 0)
+		}
+		d.tabs.Add(tab.name, grid, tab.views[0])
+	}
+}
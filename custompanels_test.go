@@ -0,0 +1,34 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuiltinTabs(t *testing.T) {
+	td := startDashboard(t, fakeStaticInfo(), &defaultTheme, 120, 40)
+	if !slices.Equal(td.tabs.names, builtinTabs) {
+		t.Errorf("the dashboard has the tabs %q, builtinTabs lists %q", td.tabs.names, builtinTabs)
+	}
+}
+
+func TestCheckPanelTabs(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		panels []CustomPanel
+		tails  []LogTailSettings
+		ok     bool
+	}{
+		{"default tabs", []CustomPanel{{Title: "queue"}}, []LogTailSettings{{Title: "nginx"}}, true},
+		{"own tab", []CustomPanel{{Title: "queue", Tab: "CI"}}, []LogTailSettings{{Title: "nginx", Tab: "CI"}}, true},
+		{"panel on a built-in tab", []CustomPanel{{Title: "queue", Tab: "Overview"}}, nil, false},
+		{"tail on a built-in tab", nil, []LogTailSettings{{Title: "nginx", Tab: "Storage"}}, false},
+		{"other case", []CustomPanel{{Title: "queue", Tab: "processes"}}, nil, false},
+		// The disk slot is on the Overview, in place of a panel.
+		{"disk slot", nil, []LogTailSettings{{Title: "nginx", Tab: "Overview", Slot: "disk"}}, true},
+	} {
+		if err := checkPanelTabs(test.panels, test.tails); (err == nil) != test.ok {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// themedPanel is a bordered panel colored by applyTheme with the PanelStyle
+// that style picks from the theme.
+type themedPanel struct {
+	view  tview.Primitive
+	style func(theme *Theme) PanelStyle
+}
+
+// dashboard holds every widget of the UI: the tabs of the dashboard page, the
+// settings, help and alert history pages, and the status line under them.
+type dashboard struct {
+	app        *tview.Application
+	staticInfo *StaticInfo
+	refresh    *refreshControl
+	alerts     *alertEngine
+
+	root   *tview.Flex
+	pages  *tview.Pages
+	tabs   *tabSet
+	status *statusLine
+
+	// Overview
+	mainGrid  *tview.Grid
+	cpuPanel  *tview.TextView
+	memPanel  *tview.TextView
+	infoPanel *tview.TextView
+	diskPanel *tview.TextView
+	tempPanel *tview.TextView
+
+	processTable  *tview.Table
+	networkPanel  *tview.TextView
+	storagePanel  *tview.TextView
+	diskIOPanel   *tview.TextView
+	sensorsPanel  *tview.TextView
+	hardwarePanel *tview.TextView
+	sessionsPanel *tview.TextView
+	pressurePanel *tview.TextView
+
+	keyBindMenu   *tview.TextView
+	alertsPanel   *tview.TextView
+	fleet         *fleet
+	settings      *tview.Form
+	themeSelector *tview.DropDown
+
+	themed    []themedPanel
+	panelTabs []*panelTab
+
+	// Log tails, and the prompt filtering them
+	tails      []*logTail
+	tailFilter *tview.InputField
+
+	// tempRanges and pressureHistory are only used from the collection
+	// goroutine.
+	tempRanges      map[string]tempRange
+	pressureHistory map[string][]float64
+}
+
+func newPanel(title string) *tview.TextView {
+	panel := tview.NewTextView()
+	panel.SetBorder(true)
+	panel.SetTitle(title)
+	panel.SetDynamicColors(true)
+	trackWidth(panel)
+	return panel
+}
+
+// dashboardExtras are the parts of the dashboard added by the config that run
+// on the local machine: the hosts of the fleet page, the commands of the
+// custom panels and the tailed files.
+type dashboardExtras struct {
+	Fleet  FleetSettings
+	Panels []CustomPanel
+	Tails  []LogTailSettings
+}
+
+// configExtras are the extras of the config. A fleet member has none: it
+// shows a remote host, where the commands and files of the config aren't,
+// and its own fleet page would open fleets within the fleet.
+func configExtras(prefs UserPreferences, fleetMember bool) dashboardExtras {
+	if fleetMember {
+		return dashboardExtras{Fleet: FleetSettings{StaleAfter: prefs.Fleet.StaleAfter}}
+	}
+	return dashboardExtras{Fleet: prefs.Fleet, Panels: prefs.Panels, Tails: prefs.Tails}
+}
+
+func newDashboard(app *tview.Application, staticInfo *StaticInfo, refresh *refreshControl, alerts *alertEngine, extras dashboardExtras) *dashboard {
+	d := &dashboard{
+		app:        app,
+		staticInfo: staticInfo,
+		refresh:    refresh,
+		alerts:     alerts,
+		tempRanges: make(map[string]tempRange),
+
+		pressureHistory: make(map[string][]float64),
+	}
+
+	//CPU section
+	d.cpuPanel = newPanel(tr("CPU"))
+	d.cpuPanel.SetScrollable(true)
+	d.cpuPanel.ScrollToBeginning()
+	//FastFetch-style section
+	d.infoPanel = newPanel(tr("System Information"))
+	//Memory section
+	d.memPanel = newPanel(tr("Memory"))
+	d.memPanel.SetScrollable(true)
+	//Disk section
+	d.diskPanel = newPanel(tr("Disk Usage"))
+	// Temperature section
+	d.tempPanel = newPanel(tr("Temperatures"))
+	d.tempPanel.SetScrollable(true)
+
+	// Overview layout
+	rightColumnLayout := tview.NewFlex().SetDirection(tview.FlexRow)
+	rightColumnLayout.AddItem(d.cpuPanel, 0, 1, true)
+	rightColumnLayout.AddItem(d.memPanel, 0, 1, false)
+	rightColumnLayout.AddItem(d.tempPanel, 0, 1, false)
+	d.mainGrid = tview.NewGrid()
+	d.mainGrid.SetRows(0, 0, 10)
+	d.mainGrid.SetColumns(0, 0)
+	d.mainGrid.SetBorder(true)
+	d.mainGrid.AddItem(d.diskPanel, 2, 0, 1, 2, 0, 0, false)
+	d.mainGrid.AddItem(d.infoPanel, 0, 0, 2, 1, 0, 0, false)
+	d.mainGrid.AddItem(rightColumnLayout, 0, 1, 2, 1, 0, 0, false)
+
+	// Processes tab
+	d.processTable = tview.NewTable()
+	d.processTable.SetBorder(true)
+	d.processTable.SetTitle(tr("Processes"))
+	d.processTable.SetFixed(1, 0)
+	d.processTable.SetSelectable(true, false)
+	processGrid := newTabGrid()
+	processGrid.SetRows(0)
+	processGrid.AddItem(d.processTable, 0, 0, 1, 1, 0, 0, true)
+
+	// Network tab
+	d.networkPanel = newPanel(tr("Network"))
+	d.networkPanel.SetScrollable(true)
+	networkGrid := newTabGrid()
+	networkGrid.SetRows(0)
+	networkGrid.AddItem(d.networkPanel, 0, 0, 1, 1, 0, 0, true)
+
+	// Storage tab
+	d.storagePanel = newPanel(tr("Filesystems"))
+	d.storagePanel.SetScrollable(true)
+	d.diskIOPanel = newPanel(tr("Disk I/O"))
+	d.diskIOPanel.SetScrollable(true)
+	storageGrid := newTabGrid()
+	storageGrid.SetRows(0, 0)
+	storageGrid.AddItem(d.storagePanel, 0, 0, 1, 1, 0, 0, true)
+	storageGrid.AddItem(d.diskIOPanel, 1, 0, 1, 1, 0, 0, false)
+
+	// Sensors tab
+	d.sensorsPanel = newPanel(tr("Sensors"))
+	d.sensorsPanel.SetScrollable(true)
+	sensorsGrid := newTabGrid()
+	sensorsGrid.SetRows(0)
+	sensorsGrid.AddItem(d.sensorsPanel, 0, 0, 1, 1, 0, 0, true)
+
+	// Hardware tab
+	d.hardwarePanel = newPanel(tr("Hardware"))
+	d.hardwarePanel.SetScrollable(true)
+	hardwareGrid := newTabGrid()
+	hardwareGrid.SetRows(0)
+	hardwareGrid.AddItem(d.hardwarePanel, 0, 0, 1, 1, 0, 0, true)
+
+	// Users tab
+	d.sessionsPanel = newPanel(tr("Sessions"))
+	d.sessionsPanel.SetScrollable(true)
+	usersGrid := newTabGrid()
+	usersGrid.SetRows(0)
+	usersGrid.AddItem(d.sessionsPanel, 0, 0, 1, 1, 0, 0, true)
+
+	// Pressure tab
+	d.pressurePanel = newPanel(tr("Pressure Stall Information"))
+	d.pressurePanel.SetScrollable(true)
+	pressureGrid := newTabGrid()
+	pressureGrid.SetRows(0)
+	pressureGrid.AddItem(d.pressurePanel, 0, 0, 1, 1, 0, 0, true)
+
+	d.tabs = newTabSet(app)
+	d.tabs.Add("Overview", d.mainGrid, d.cpuPanel)
+	d.tabs.Add("Processes", processGrid, d.processTable)
+	d.tabs.Add("Network", networkGrid, d.networkPanel)
+	d.tabs.Add("Storage", storageGrid, d.storagePanel)
+	d.tabs.Add("Sensors", sensorsGrid, d.sensorsPanel)
+	d.tabs.Add("Hardware", hardwareGrid, d.hardwarePanel)
+	d.tabs.Add("Users", usersGrid, d.sessionsPanel)
+	// Without PSI (older kernels, other systems) the tab would stay empty.
+	if staticInfo.Pressure {
+		d.tabs.Add("Pressure", pressureGrid, d.pressurePanel)
+	}
+	d.tabs.Switch(0)
+
+	// Fleet
+	d.fleet = newFleet(app, extras.Fleet, staticInfo.Hostname)
+	d.fleet.table.SetSelectedFunc(func(row, column int) {
+		d.fleet.open(row, func() {
+			d.pages.SwitchToPage("dashboard")
+		})
+	})
+
+	d.themed = []themedPanel{
+		{d.cpuPanel, func(theme *Theme) PanelStyle { return theme.CPUPanel }},
+		{d.memPanel, func(theme *Theme) PanelStyle { return theme.MemPanel }},
+		{d.infoPanel, func(theme *Theme) PanelStyle { return theme.InfoPanel }},
+		{d.tempPanel, func(theme *Theme) PanelStyle { return theme.TempPanel }},
+		{d.diskPanel, func(theme *Theme) PanelStyle { return theme.DiskPanel }},
+		{d.processTable, func(theme *Theme) PanelStyle { return theme.ProcPanel }},
+		{d.networkPanel, func(theme *Theme) PanelStyle { return theme.NetPanel }},
+		{d.storagePanel, func(theme *Theme) PanelStyle { return theme.DiskPanel }},
+		{d.diskIOPanel, func(theme *Theme) PanelStyle { return theme.DiskPanel }},
+		{d.sensorsPanel, func(theme *Theme) PanelStyle { return theme.TempPanel }},
+		{d.hardwarePanel, func(theme *Theme) PanelStyle { return theme.InfoPanel }},
+		{d.sessionsPanel, func(theme *Theme) PanelStyle { return theme.InfoPanel }},
+		{d.pressurePanel, func(theme *Theme) PanelStyle { return theme.CPUPanel }},
+		{d.fleet.table, func(theme *Theme) PanelStyle { return theme.InfoPanel }},
+	}
+
+	// Config-defined panels, on their own tabs after the built-in ones
+	d.addCustomPanels(extras.Panels)
+	d.addLogTails(extras.Tails)
+	d.addPanelTabs()
+
+	// Settings
+	d.settings = tview.NewForm()
+	d.settings.SetBorder(true)
+	d.settings.SetTitle(tr("Settings - ESC or 's' to go back"))
+	d.themeSelector = tview.NewDropDown()
+	d.themeSelector.SetLabel(tr("Select a theme (hit Enter): "))
+	d.themeSelector.SetOptions(themesList, nil)
+	d.themeSelector.SetCurrentOption(0)
+	d.settings.AddFormItem(d.themeSelector)
+	d.settings.AddButton(tr("Save and close"), func() {
+		_, selection := d.themeSelector.GetCurrentOption()
+		currentTheme = themeByName(selection)
+		d.applyTheme(currentTheme)
+		userPrefs.ThemeName = selection
+		saveToFile(userPrefs)
+		d.pages.SwitchToPage("dashboard")
+	})
+
+	// Help
+	d.keyBindMenu = tview.NewTextView()
+	d.keyBindMenu.SetBorder(true)
+	d.keyBindMenu.SetTitle(tr("Keybinds - ESC or 'h' to go back"))
+	keyBinds := [][2]string{
+		{"'q'/CTRL + C", "quit the application"},
+		{d.tabs.KeyRange(), "switch between the dashboard tabs"},
+		{"'s'", "open the settings page"},
+		{"TAB/Arrow keys", "navigate in the settings page"},
+		{"ESC", "quit the settings/help page"},
+		{"'h'", "open the help page (this page)"},
+		{"'p'/SPACE", "pause or resume the refresh"},
+		{"'+'/'-'", "increase or decrease the refresh interval"},
+		{"'a'", "open the alert history page"},
+		{"'f'", "open the fleet page, Enter on a host opens its dashboard"},
+		{"'/'", "filter the log panels of the current tab"},
+	}
+	var helpText string
+	for _, keyBind := range keyBinds {
+		helpText += keyBind[0] + " - " + tr(keyBind[1]) + "\n"
+	}
+	d.keyBindMenu.SetText(helpText + "\n\n" + tr("Made by @Hash-AK (https://github.com/hash-ak)"))
+
+	// Alerts
+	d.alertsPanel = newPanel(tr("Alert history - ESC or 'a' to go back"))
+	d.alertsPanel.SetText(renderAlertHistory(currentTheme, alerts))
+
+	d.pages = tview.NewPages()
+	d.pages.AddPage("settings", d.settings, true, false)
+	d.pages.AddPage("help", d.keyBindMenu, true, false)
+	d.pages.AddPage("alerts", d.alertsPanel, true, false)
+	d.pages.AddPage("fleet", d.fleet.table, true, false)
+	d.pages.AddPage("dashboard", d.tabs.layout, true, true)
+
+	// Status line, under every page
+	d.status = newStatusLine(d.pages, d.tabs, refresh, alerts, staticInfo.Hostname)
+	d.pages.SetChangedFunc(func() {
+		d.status.Render(currentTheme)
+	})
+	d.root = tview.NewFlex().SetDirection(tview.FlexRow)
+	d.root.AddItem(d.pages, 0, 1, true)
+	d.root.AddItem(d.tailFilter, 0, 0, false)
+	d.root.AddItem(d.status.view, 1, 0, false)
+
+	d.applyTheme(currentTheme)
+	d.status.Render(currentTheme)
+	return d
+}
+
+func newTabGrid() *tview.Grid {
+	grid := tview.NewGrid()
+	grid.SetColumns(0)
+	grid.SetBorder(true)
+	return grid
+}
+
+func applyPanelStyle(view tview.Primitive, style PanelStyle) {
+	switch panel := view.(type) {
+	case *tview.TextView:
+		panel.SetBorderColor(style.BorderColor)
+		panel.SetTitleColor(style.TitleColor)
+		panel.SetTextColor(style.TextColor)
+		panel.SetBackgroundColor(style.BackGroundColor)
+	case *tview.Table:
+		panel.SetBorderColor(style.BorderColor)
+		panel.SetTitleColor(style.TitleColor)
+		panel.SetBackgroundColor(style.BackGroundColor)
+	}
+}
+
+func (d *dashboard) applyTheme(theme *Theme) {
+	for _, panel := range d.themed {
+		applyPanelStyle(panel.view, panel.style(theme))
+	}
+
+	d.keyBindMenu.SetBorderColor(theme.InfoPanel.BorderColor)
+	d.keyBindMenu.SetTextColor(theme.InfoPanel.TextColor)
+	d.keyBindMenu.SetBackgroundColor(theme.Backgroundcolor)
+	d.status.view.SetTextColor(theme.InfoPanel.TextColor)
+	d.status.view.SetBackgroundColor(theme.Backgroundcolor)
+	d.alertsPanel.SetBorderColor(theme.InfoPanel.BorderColor)
+	d.alertsPanel.SetTitleColor(theme.InfoPanel.TitleColor)
+	d.alertsPanel.SetTextColor(theme.InfoPanel.TextColor)
+	d.alertsPanel.SetBackgroundColor(theme.Backgroundcolor)
+	tview.Styles.PrimitiveBackgroundColor = theme.Backgroundcolor
+	d.tabs.applyTheme(theme)
+
+	d.themeSelector.SetLabelColor(theme.InfoPanel.TitleColor)
+	d.themeSelector.SetFieldTextColor(theme.InfoPanel.TextColor)
+	d.themeSelector.SetFieldBackgroundColor(theme.InfoPanel.BackGroundColor)
+	d.themeSelector.SetListStyles(theme.DropDownOptionStyle, theme.DropDownSelectedStyle)
+	d.settings.SetBackgroundColor(theme.Backgroundcolor)
+	d.tailFilter.SetLabelColor(theme.InfoPanel.TitleColor)
+	d.tailFilter.SetFieldTextColor(theme.InfoPanel.TextColor)
+	d.tailFilter.SetFieldBackgroundColor(theme.InfoPanel.BackGroundColor)
+	d.tailFilter.SetBackgroundColor(theme.Backgroundcolor)
+}
+
+func (d *dashboard) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	if d.app.GetFocus() == d.tailFilter {
+		return event
+	}
+	if event.Rune() == 'q' {
+		d.app.Stop()
+		return nil
+	}
+	currentPage, _ := d.pages.GetFrontPage()
+	if event.Rune() == 's' {
+		if currentPage == "dashboard" {
+			d.pages.SwitchToPage("settings")
+		} else if currentPage == "settings" {
+			d.pages.SwitchToPage("dashboard")
+		}
+		return nil
+	}
+	if event.Rune() == 'h' {
+		if currentPage == "dashboard" {
+			d.pages.SwitchToPage("help")
+		} else if currentPage == "help" {
+			d.pages.SwitchToPage("dashboard")
+		}
+	}
+	if event.Rune() == 'f' {
+		if currentPage == "dashboard" {
+			d.pages.SwitchToPage("fleet")
+		} else if currentPage == "fleet" {
+			d.pages.SwitchToPage("dashboard")
+		}
+		return nil
+	}
+	if event.Rune() == 'a' {
+		if currentPage == "dashboard" {
+			d.pages.SwitchToPage("alerts")
+		} else if currentPage == "alerts" {
+			d.pages.SwitchToPage("dashboard")
+		}
+		return nil
+	}
+	if currentPage == "dashboard" {
+		switch event.Rune() {
+		case 'p', ' ':
+			d.refresh.TogglePause()
+			return nil
+		case '+', '=':
+			d.refresh.Slower()
+			return nil
+		case '-', '_':
+			d.refresh.Faster()
+			return nil
+		case '/':
+			d.openTailFilter()
+			return nil
+		}
+		if event.Rune() >= '0' && event.Rune() <= '9' {
+			// '0' is the tenth tab.
+			if d.tabs.Switch((int(event.Rune()-'0') + 9) % 10) {
+				d.status.Render(currentTheme)
+			}
+			return nil
+		}
+	}
+	if event.Key() == tcell.KeyEscape {
+		if currentPage == "settings" || currentPage == "help" || currentPage == "alerts" || currentPage == "fleet" {
+			d.pages.SwitchToPage("dashboard")
+		}
+	}
+	return event
+}
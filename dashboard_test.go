@@ -0,0 +1,309 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current output")
+
+// goldenSizes are the terminal sizes the dashboard is checked at.
+var goldenSizes = [][2]int{{80, 24}, {120, 40}, {200, 60}}
+
+// testDashboard is a dashboard running on a simulated screen.
+type testDashboard struct {
+	*dashboard
+	screen tcell.SimulationScreen
+	source sampleSource
+	theme  *Theme
+}
+
+// TestMain shows the times in UTC whatever the time zone of the machine
+// running the tests. It is set once, as the log tails of a test keep reading
+// it after the test.
+func TestMain(m *testing.M) {
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
+
+// useDefaultPreferences sets the preferences of a new config file, with "."
+// as the decimal separator whatever the locale of the machine running the
+// tests, and puts the previous ones back at the end of the test.
+func useDefaultPreferences(t *testing.T) {
+	t.Helper()
+	savedPrefs, savedTheme, savedMessages := userPrefs, currentTheme, messages
+	t.Cleanup(func() {
+		userPrefs, currentTheme, messages = savedPrefs, savedTheme, savedMessages
+	})
+	userPrefs = defaultPreferences()
+	if _, err := toml.Decode(defaultUserPreferencesTOML, &userPrefs); err != nil {
+		t.Fatal(err)
+	}
+	userPrefs.Format.DecimalSeparator = "."
+	messages = nil
+}
+
+// startDashboard runs the dashboard of a machine on a simulated screen of
+// the given size, with samples of the fake machine.
+func startDashboard(t *testing.T, staticInfo StaticInfo, theme *Theme, width, height int) *testDashboard {
+	t.Helper()
+	useDefaultPreferences(t)
+	return runDashboard(t, staticInfo, theme, width, height)
+}
+
+// runDashboard is startDashboard with the preferences already set by the
+// test, after useDefaultPreferences.
+func runDashboard(t *testing.T, staticInfo StaticInfo, theme *Theme, width, height int) *testDashboard {
+	t.Helper()
+	currentTheme = theme
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	app := tview.NewApplication()
+	app.SetScreen(screen)
+	screen.SetSize(width, height)
+
+	d := newDashboard(app, &staticInfo, newRefreshControl(time.Second), newAlertEngine(nil), configExtras(userPrefs, false))
+	d.status.clock = func() time.Time { return fakeTime }
+	app.SetRoot(d.root, true)
+	app.SetInputCapture(d.handleKey)
+
+	done := make(chan error)
+	go func() {
+		done <- app.Run()
+	}()
+	t.Cleanup(func() {
+		app.Stop()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	td := &testDashboard{dashboard: d, screen: screen, source: newFakeSource(5), theme: theme}
+	// Stopping the application before it runs would make it open the
+	// terminal instead of the simulated screen.
+	td.sync()
+	return td
+}
+
+// sync waits until the application has drawn every queued update.
+func (td *testDashboard) sync() {
+	drawn := make(chan struct{})
+	td.app.QueueUpdate(func() {
+		close(drawn)
+	})
+	<-drawn
+}
+
+// tick collects the next fake sample and draws it, like one refresh tick.
+// The bars are sized from the previous draw, so the first tick of a test
+// draws bars of the default width.
+func (td *testDashboard) tick(t *testing.T) {
+	t.Helper()
+	sample, err := td.source.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	td.updateInfos(td.theme, sample, td.alerts.FiringPanels())
+	td.app.QueueUpdateDraw(func() {
+		td.status.Render(td.theme)
+	})
+	td.sync()
+}
+
+// switchTab shows the i-th tab.
+func (td *testDashboard) switchTab(i int) {
+	td.app.QueueUpdateDraw(func() {
+		td.tabs.Switch(i)
+	})
+	td.sync()
+}
+
+// screenDump writes the screen as its text, followed by the style of every
+// cell: each style gets a letter, described under the text, and a second
+// grid gives the letter of each cell.
+func screenDump(screen tcell.SimulationScreen) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	cells, width, height := screen.GetContents()
+	var text, styleMap strings.Builder
+	var styles []tcell.Style
+	for y := range height {
+		for x := range width {
+			cell := cells[y*width+x]
+			if len(cell.Runes) == 0 {
+				text.WriteByte(' ')
+			} else {
+				text.WriteString(string(cell.Runes))
+			}
+			index := -1
+			for i, style := range styles {
+				if style == cell.Style {
+					index = i
+				}
+			}
+			if index < 0 {
+				index = len(styles)
+				styles = append(styles, cell.Style)
+			}
+			if index < len(letters) {
+				styleMap.WriteByte(letters[index])
+			} else {
+				styleMap.WriteByte('?')
+			}
+		}
+		text.WriteByte('\n')
+		styleMap.WriteByte('\n')
+	}
+	var legend strings.Builder
+	for i, style := range styles {
+		foreground, background, attributes := style.Decompose()
+		fmt.Fprintf(&legend, "%c: %s on %s%s\n", letters[min(i, len(letters)-1)], foreground, background, attributeNames(attributes))
+	}
+	return text.String() + "-- styles --\n" + legend.String() + "-- style map --\n" + styleMap.String()
+}
+
+func attributeNames(attributes tcell.AttrMask) string {
+	var names string
+	for _, attribute := range []struct {
+		mask tcell.AttrMask
+		name string
+	}{
+		{tcell.AttrBold, "bold"},
+		{tcell.AttrDim, "dim"},
+		{tcell.AttrItalic, "italic"},
+		{tcell.AttrUnderline, "underline"},
+		{tcell.AttrReverse, "reverse"},
+		{tcell.AttrBlink, "blink"},
+		{tcell.AttrStrikeThrough, "strikethrough"},
+	} {
+		if attributes&attribute.mask != 0 {
+			names += " " + attribute.name
+		}
+	}
+	return names
+}
+
+// checkGolden compares the screen with testdata/<name>.golden, or rewrites
+// the file with -update.
+func checkGolden(t *testing.T, name string, screen tcell.SimulationScreen) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	got := screenDump(screen)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got == string(want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var gotLine, wantLine string
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine {
+			t.Fatalf("%s differs at line %d:\ngot:  %q\nwant: %q\n(run go test -update if the change is intended)", path, i+1, gotLine, wantLine)
+		}
+	}
+}
+
+// goldenName turns a theme name and a suffix into a golden file name, e.g.
+// "overview-snow-day-80x24".
+func goldenName(parts ...string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.Join(parts, "-"), " ", "-"))
+}
+
+func TestOverviewGolden(t *testing.T) {
+	for _, themeName := range themesList {
+		for _, size := range goldenSizes {
+			name := goldenName("overview", themeName, fmt.Sprintf("%dx%d", size[0], size[1]))
+			t.Run(name, func(t *testing.T) {
+				td := startDashboard(t, fakeStaticInfo(), themeByName(themeName), size[0], size[1])
+				td.tick(t)
+				td.tick(t)
+				checkGolden(t, name, td.screen)
+			})
+		}
+	}
+}
+
+func TestTabsGolden(t *testing.T) {
+	for _, themeName := range themesList {
+		t.Run(goldenName(themeName), func(t *testing.T) {
+			td := startDashboard(t, fakeStaticInfo(), themeByName(themeName), 120, 40)
+			td.tick(t)
+			for i, tabName := range td.tabs.names {
+				td.switchTab(i)
+				td.tick(t)
+				checkGolden(t, goldenName("tab", tabName, themeName), td.screen)
+			}
+		})
+	}
+}
+
+func TestUnavailableGolden(t *testing.T) {
+	staticInfo := fakeStaticInfo()
+	staticInfo.CPUModel, staticInfo.CPUVendor, staticInfo.CPUFamily = "", "", ""
+	staticInfo.Unavailable = probeErrors{"cpu": "no CPU information", "cores": "not implemented yet", "kernel": "permission denied"}
+	td := startDashboard(t, staticInfo, &defaultTheme, 120, 40)
+	sample := fakeSample(0)
+	sample.Unavailable = probeErrors{
+		"memory":    "open /proc/meminfo: no such file or directory",
+		"load":      "not implemented yet",
+		"temps":     "runtime error: index out of range [0] with length 0",
+		"processes": "open /proc: permission denied",
+		"sessions":  "open /var/run/utmp: permission denied",
+	}
+	sample.MemTotal, sample.Temps, sample.Processes, sample.Sessions = 0, nil, nil, nil
+	td.source = &fakeSource{samples: []*Sample{sample}}
+	td.tick(t)
+	for i, tabName := range td.tabs.names {
+		if tabName != "Overview" && tabName != "Processes" && tabName != "Hardware" && tabName != "Users" {
+			continue
+		}
+		td.switchTab(i)
+		td.tick(t)
+		checkGolden(t, goldenName("unavailable", tabName), td.screen)
+	}
+}
+
+func TestFakeSourceScript(t *testing.T) {
+	failure := fmt.Errorf("agent unreachable")
+	source := &fakeSource{
+		samples: []*Sample{fakeSample(0), nil, fakeSample(2)},
+		errors:  []error{nil, failure},
+	}
+	for i, want := range []struct {
+		cpu float64
+		err error
+	}{{35, nil}, {-1, failure}, {55, nil}, {55, nil}} {
+		sample, err := source.Collect()
+		if err != want.err {
+			t.Errorf("call %d: error %v, want %v", i, err, want.err)
+		}
+		if want.cpu < 0 {
+			if sample != nil {
+				t.Errorf("call %d: sample %v, want nil", i, sample)
+			}
+		} else if sample == nil || sample.CPUTotal != want.cpu {
+			t.Errorf("call %d: sample %v, want CPUTotal %v", i, sample, want.cpu)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// DiskSettings is the [Disks] table of the config file. It chooses which
+// filesystems are shown and when the inode usage turns yellow or red:
+//
+//	[Disks]
+//	IncludeFSTypes = []
+//	ExcludeFSTypes = ["squashfs", "overlay", "tmpfs", "devtmpfs"]
+//	IncludeMounts = []
+//	ExcludeMounts = ["/snap/*", "/var/lib/docker/*"]
+//	InodeYellowPercent = 70
+//	InodeRedPercent = 90
+//
+// When an Include list is not empty, a filesystem has to match it to be
+// shown. The Exclude lists are applied after that.
+type DiskSettings struct {
+	IncludeFSTypes     []string `toml:"IncludeFSTypes"`
+	ExcludeFSTypes     []string `toml:"ExcludeFSTypes"`
+	IncludeMounts      []string `toml:"IncludeMounts"`
+	ExcludeMounts      []string `toml:"ExcludeMounts"`
+	InodeYellowPercent float64  `toml:"InodeYellowPercent"`
+	InodeRedPercent    float64  `toml:"InodeRedPercent"`
+}
+
+var defaultDiskSettings = DiskSettings{
+	IncludeFSTypes:     []string{},
+	ExcludeFSTypes:     []string{"squashfs", "overlay", "tmpfs", "devtmpfs"},
+	IncludeMounts:      []string{},
+	ExcludeMounts:      []string{"/snap/*", "/var/lib/docker/*"},
+	InodeYellowPercent: 70,
+	InodeRedPercent:    90,
+}
+
+// mountMatches reports whether the mountpoint, or one of its parent
+// directories, matches the glob pattern. This way "/snap/*" also matches
+// "/snap/core22/1380".
+func mountMatches(pattern, mountpoint string) bool {
+	for dir := mountpoint; ; dir = path.Dir(dir) {
+		if matched, _ := path.Match(pattern, dir); matched {
+			return true
+		}
+		if dir == "/" || dir == "." {
+			return false
+		}
+	}
+}
+
+func (settings DiskSettings) shown(fstype, mountpoint string) bool {
+	if len(settings.IncludeFSTypes) > 0 && !containsString(settings.IncludeFSTypes, fstype) {
+		return false
+	}
+	if containsString(settings.ExcludeFSTypes, fstype) {
+		return false
+	}
+	if len(settings.IncludeMounts) > 0 {
+		included := false
+		for _, pattern := range settings.IncludeMounts {
+			if mountMatches(pattern, mountpoint) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range settings.ExcludeMounts {
+		if mountMatches(pattern, mountpoint) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inodeColor colors the inode usage with the [Disks] thresholds, which are
+// separate from the bar ones: running out of inodes breaks a filesystem just
+// like running out of space, even when it looks nearly empty.
+func inodeColor(theme *Theme, settings DiskSettings, percent float64) string {
+	if percent >= settings.InodeRedPercent {
+		return fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
+	} else if percent >= settings.InodeYellowPercent {
+		return fmt.Sprintf("[%s]", theme.BarYellow.TrueColor().String())
+	}
+	return fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
+}
+
+// renderDisks lays the filesystems out in columns: mountpoint, device, type,
+// usage bar, inode usage and mount options, with read-only ones flagged. The
+// bars take what the other columns leave of the panel's width.
+func renderDisks(theme *Theme, disks []DiskSample, settings DiskSettings, panelWidth int) string {
+	if len(disks) == 0 {
+		return tr("No filesystems found.")
+	}
+	rows := diskRows(theme, disks, settings, 0)
+	return alignColumns(diskRows(theme, disks, settings, fitBarWidth(panelWidth, columnsWidth(rows))))
+}
+
+func diskRows(theme *Theme, disks []DiskSample, settings DiskSettings, barWidth int) [][]string {
+	header := []string{tr("Mount"), tr("Device"), tr("Type"), tr("Usage"), tr("Inodes"), tr("Options")}
+	rows := [][]string{header}
+	for _, usage := range disks {
+		diskBar, colorCode := createBar(theme, usage.UsedPercent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, barWidth)
+		usageText := fmt.Sprintf("%s %s%s[-] (%s/%s)", diskBar, colorCode, formatPercent(usage.UsedPercent), formatBytes(usage.Used), formatBytes(usage.Total))
+		inodesText := "-"
+		if usage.InodesTotal > 0 {
+			inodesText = fmt.Sprintf("%s%s[-]", inodeColor(theme, settings, usage.InodesUsedPercent), formatPercent(usage.InodesUsedPercent))
+		}
+		options := tview.Escape(strings.Join(usage.Opts, ","))
+		if usage.ReadOnly {
+			options = fmt.Sprintf("[%s]RO[-] %s", theme.BarRed.TrueColor().String(), options)
+		}
+		rows = append(rows, []string{tview.Escape(usage.Mountpoint), tview.Escape(usage.Device), usage.Fstype, usageText, inodesText, options})
+	}
+	return rows
+}
+
+// columnWidths is the width of each column of the rows.
+func columnWidths(rows [][]string) []int {
+	var widths []int
+	for _, row := range rows {
+		for column, cell := range row {
+			if column >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := tview.TaggedStringWidth(cell); width > widths[column] {
+				widths[column] = width
+			}
+		}
+	}
+	return widths
+}
+
+// columnsWidth is the width of the rows once aligned by alignColumns.
+func columnsWidth(rows [][]string) int {
+	width := 0
+	for _, columnWidth := range columnWidths(rows) {
+		width += columnWidth + 1
+	}
+	return width - 1
+}
+
+// alignColumns pads the cells (which may contain color tags) so that the
+// columns line up, the first row being a bold header.
+func alignColumns(rows [][]string) string {
+	widths := columnWidths(rows)
+	var text string
+	for i, row := range rows {
+		for column, cell := range row {
+			if i == 0 {
+				cell = "[::b]" + cell + "[::-]"
+			}
+			text += cell
+			if column < len(row)-1 {
+				text += strings.Repeat(" ", widths[column]-tview.TaggedStringWidth(cell)+1)
+			}
+		}
+		text += "\n"
+	}
+	return text
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// fakeTime is the time of the first fake sample, and of the fake clock.
+var fakeTime = time.Date(2025, time.March, 14, 9, 26, 53, 0, time.UTC)
+
+// fakeSource is a scripted sampleSource: each call to Collect returns the
+// next sample and error of the script, then the last ones again once the
+// script is over.
+type fakeSource struct {
+	samples []*Sample
+	errors  []error
+	calls   int
+}
+
+func (source *fakeSource) Collect() (*Sample, error) {
+	i := min(source.calls, len(source.samples)-1)
+	source.calls++
+	var err error
+	if i < len(source.errors) {
+		err = source.errors[i]
+	}
+	return source.samples[i], err
+}
+
+// newFakeSource scripts steps samples of a busy machine, the load rising at
+// each step.
+func newFakeSource(steps int) *fakeSource {
+	source := &fakeSource{}
+	for step := range steps {
+		source.samples = append(source.samples, fakeSample(step))
+	}
+	return source
+}
+
+// fakeStaticInfo describes the machine of the fake samples.
+func fakeStaticInfo() StaticInfo {
+	return StaticInfo{
+		Logo:          loadLogo([]string{"debian"}, "testdata"),
+		OS:            "Debian",
+		OSFamily:      "debian",
+		OSVersion:     "12.9",
+		KernelVersion: "6.1.0-30-amd64",
+		KernelArch:    "x86_64",
+		Hostname:      "buildbox",
+		CPUPhysCore:   4,
+		CPULogCore:    8,
+		CPUModel:      "AMD Ryzen 7 5800U",
+
+		CPUVendor:          "AuthenticAMD",
+		CPUFamily:          "25",
+		CPUMHz:             1900,
+		CPUCacheSize:       512,
+		Virtualization:     "kvm",
+		VirtualizationRole: "host",
+		BootTime:           uint64(fakeTime.Add(-50 * time.Hour).Unix()),
+
+		User:       "alice",
+		Shell:      "bash",
+		Terminal:   "xterm-256color",
+		Packages:   "1523 (dpkg)",
+		InitSystem: "systemd",
+		Locale:     "en_US.UTF-8",
+
+		Pressure: true,
+	}
+}
+
+// fakeStaticSource describes the fake machine to readStaticInfo. The calls
+// named in failures return their error instead, or panic with it when
+// panics is set.
+type fakeStaticSource struct {
+	failures map[string]error
+	panics   bool
+}
+
+func (source fakeStaticSource) fail(call string) error {
+	err := source.failures[call]
+	if err != nil && source.panics {
+		panic(err)
+	}
+	return err
+}
+
+func (source fakeStaticSource) PlatformInformation() (string, string, string, error) {
+	if err := source.fail("PlatformInformation"); err != nil {
+		return "", "", "", err
+	}
+	return "debian", "debian", "12.9", nil
+}
+
+func (source fakeStaticSource) CPUCounts(logical bool) (int, error) {
+	if logical {
+		return 8, source.fail("CPUCounts logical")
+	}
+	if err := source.fail("CPUCounts"); err != nil {
+		return 0, err
+	}
+	return 4, nil
+}
+
+func (source fakeStaticSource) CPUInfo() ([]cpu.InfoStat, error) {
+	if err := source.fail("CPUInfo"); err != nil {
+		return nil, err
+	}
+	return []cpu.InfoStat{{ModelName: "AMD Ryzen 7 5800U", VendorID: "AuthenticAMD", Family: "25", Mhz: 1900, CacheSize: 512}}, nil
+}
+
+func (source fakeStaticSource) HostInfo() (*host.InfoStat, error) {
+	if err := source.fail("HostInfo"); err != nil {
+		return nil, err
+	}
+	return &host.InfoStat{Hostname: "buildbox", VirtualizationSystem: "kvm", VirtualizationRole: "host"}, nil
+}
+
+func (source fakeStaticSource) KernelVersion() (string, error) {
+	if err := source.fail("KernelVersion"); err != nil {
+		return "", err
+	}
+	return "6.1.0-30-amd64", nil
+}
+
+func (source fakeStaticSource) KernelArch() (string, error) {
+	if err := source.fail("KernelArch"); err != nil {
+		return "", err
+	}
+	return "x86_64", nil
+}
+
+// fakeSample is the sample of the given step. The values are picked so that
+// every color of the bars shows up.
+func fakeSample(step int) *Sample {
+	load := float64(step) * 10
+	const gib = 1 << 30
+	return &Sample{
+		Time:   fakeTime.Add(time.Duration(step) * time.Second),
+		Uptime: 50 * 3600,
+
+		CPUTotal:   35 + load,
+		CPUPerCore: []float64{12, 48 + load, 67, 95, 3, 25 + load, 81, 50},
+		CPUTimes: CPUTimesSample{
+			User: 22 + load, System: 8, Iowait: 2.5, Irq: 0.5, Softirq: 1, Steal: 0.5, Nice: 0.5, Idle: 65 - load,
+		},
+
+		Load1:  2.5 + load/10,
+		Load5:  1.75,
+		Load15: 1.2,
+
+		MemTotal:       16 * gib,
+		MemUsed:        9 * gib,
+		MemUsedPercent: 56.25,
+		MemAvailable:   6 * gib,
+		MemFree:        1 * gib,
+		MemBuffers:     512 << 20,
+		MemCached:      5 * gib,
+		MemShared:      1 * gib,
+		MemDirty:       24 << 20,
+		MemWriteBack:   0,
+
+		SwapTotal:       4 * gib,
+		SwapUsed:        3 * gib,
+		SwapUsedPercent: 75,
+		SwapInRate:      4096,
+		SwapOutRate:     0,
+
+		Disks: []DiskSample{
+			{
+				Mountpoint: "/", Device: "/dev/nvme0n1p2", Fstype: "ext4", Opts: []string{"rw", "relatime"},
+				Total: 500 * gib, Used: 210 * gib, UsedPercent: 42,
+				InodesTotal: 32000000, InodesUsed: 1600000, InodesUsedPercent: 5,
+			},
+			{
+				Mountpoint: "/boot/efi", Device: "/dev/nvme0n1p1", Fstype: "vfat", Opts: []string{"rw"},
+				Total: 512 << 20, Used: 300 << 20, UsedPercent: 58.59,
+			},
+			{
+				Mountpoint: "/mnt/backup", Device: "/dev/sda1", Fstype: "ext4", Opts: []string{"ro"}, ReadOnly: true,
+				Total: 2000 * gib, Used: 1900 * gib, UsedPercent: 95,
+				InodesTotal: 120000000, InodesUsed: 30000000, InodesUsedPercent: 25,
+			},
+		},
+		DiskIO: []DiskIOSample{
+			{Name: "nvme0n1", ReadBytes: 80 * gib, WriteBytes: 35 * gib, ReadRate: 12 << 20, WriteRate: 3 << 20},
+			{Name: "sda", ReadBytes: 2 * gib, WriteBytes: 1 * gib},
+		},
+		Temps: []TempSample{
+			{SensorKey: "k10temp_tctl", Temperature: 61 + load, High: 90, Critical: 100},
+			{SensorKey: "nvme_composite", Temperature: 44, High: 80, Critical: 85},
+			{SensorKey: "acpitz", Temperature: 27.8},
+		},
+		Network: []NetSample{
+			{Name: "lo", Addrs: []string{"127.0.0.1/8", "::1/128"}, Up: true, BytesRecv: 1 << 20, BytesSent: 1 << 20},
+			{
+				Name: "eth0", Addrs: []string{"192.168.1.20/24"}, Up: true,
+				BytesRecv: 12 * gib, BytesSent: 3 * gib, RecvRate: 1500000 + load*1000, SendRate: 250000, Errors: 3, Drops: 12,
+			},
+			{Name: "wlan0", Up: false},
+		},
+		Processes: []ProcessSample{
+			{PID: 4242, Name: "cc1plus", User: "alice", Status: "R", CPUPercent: 98.5, MemPercent: 3.2, RSS: 512 << 20, Threads: 1, Command: "/usr/lib/gcc/x86_64-linux-gnu/12/cc1plus -quiet main.cpp"},
+			{PID: 1337, Name: "firefox", User: "alice", Status: "S", CPUPercent: 22 + load, MemPercent: 12.5, RSS: 2 * gib, Threads: 94, Command: "/usr/lib/firefox/firefox"},
+			{PID: 812, Name: "postgres", User: "postgres", Status: "S", CPUPercent: 4.1, MemPercent: 1.8, RSS: 300 << 20, Threads: 1, Command: "postgres: checkpointer"},
+			{PID: 1, Name: "systemd", User: "root", Status: "S", CPUPercent: 0, MemPercent: 0.1, RSS: 12 << 20, Threads: 1, Command: "/sbin/init"},
+		},
+		Sessions: []SessionSample{
+			{User: "alice", Terminal: "tty1", Started: fakeTime.Add(-3 * time.Hour), Idle: 2 * time.Minute},
+			{User: "bob", Terminal: "pts/0", Host: "203.0.113.7", Started: fakeTime.Add(-5 * time.Minute)},
+		},
+
+		Cgroup: &CgroupSample{
+			Version: 2, Path: "/user.slice/user-1000.slice",
+			MemoryCurrent: 9 * gib, PidsCurrent: 412,
+		},
+		Pressure: PressureSample{
+			"cpu":    {Some: PressureLine{Avg10: 12.5 + load, Avg60: 8.2, Avg300: 3.1, Total: 123456789}},
+			"memory": {Some: PressureLine{Avg10: 0.5, Avg60: 0.2}, Full: PressureLine{Avg10: 0.1}, HasFull: true},
+			"io":     {Some: PressureLine{Avg10: 35, Avg60: 20.4, Avg300: 9.8}, Full: PressureLine{Avg10: 28, Avg60: 15}, HasFull: true},
+		},
+	}
+}
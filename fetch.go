@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	// tviewTag matches a tview style tag, e.g. "[red]", "[#ff0000::b]" or
+	// "[-:-:-]".
+	tviewTag = regexp.MustCompile(`^\[([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?(?::([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?)?(?::([lbidrus]+|-)?)?\]`)
+	// tviewEscaped matches text escaped with tview.Escape, e.g. "[RO[]".
+	tviewEscaped = regexp.MustCompile(`^\[([a-zA-Z0-9_,;: \-\."#]+\[*)\[\]`)
+)
+
+// ansiStyle is the style being built while converting tview tags to ANSI.
+type ansiStyle struct {
+	fg, bg string
+	attrs  string
+}
+
+func (style ansiStyle) sequence() string {
+	sequence := "\x1b[0m"
+	codes := map[rune]string{'b': "1", 'd': "2", 'i': "3", 'u': "4", 'l': "5", 'r': "7", 's': "9"}
+	for _, attr := range style.attrs {
+		if code, ok := codes[attr]; ok {
+			sequence += "\x1b[" + code + "m"
+		}
+	}
+	if style.fg != "" {
+		sequence += "\x1b[38;2;" + style.fg + "m"
+	}
+	if style.bg != "" {
+		sequence += "\x1b[48;2;" + style.bg + "m"
+	}
+	return sequence
+}
+
+// ansiColor turns a tview color into the "r;g;b" of a true color escape
+// sequence. ok is false when it isn't a color at all.
+func ansiColor(name string) (string, bool) {
+	if strings.HasPrefix(name, "#") {
+		color := tcell.GetColor(name)
+		r, g, b := color.RGB()
+		return fmt.Sprintf("%d;%d;%d", r, g, b), true
+	}
+	color, ok := tcell.ColorNames[strings.ToLower(name)]
+	if !ok {
+		return "", false
+	}
+	r, g, b := color.RGB()
+	return fmt.Sprintf("%d;%d;%d", r, g, b), true
+}
+
+// tviewToANSI converts text with tview style tags (like the one drawn in the
+// panels) into text with ANSI escape sequences, for a terminal outside of
+// the dashboard.
+func tviewToANSI(text string) string {
+	var builder strings.Builder
+	var style ansiStyle
+	for i := 0; i < len(text); {
+		if text[i] != '[' {
+			builder.WriteByte(text[i])
+			i++
+			continue
+		}
+		if match := tviewEscaped.FindStringSubmatch(text[i:]); match != nil {
+			builder.WriteString("[" + match[1] + "]")
+			i += len(match[0])
+			continue
+		}
+		match := tviewTag.FindStringSubmatch(text[i:])
+		if match == nil || match[0] == "[]" {
+			builder.WriteByte('[')
+			i++
+			continue
+		}
+		valid := true
+		newStyle := style
+		for part, value := range []string{match[1], match[2]} {
+			target := &newStyle.fg
+			if part == 1 {
+				target = &newStyle.bg
+			}
+			switch value {
+			case "":
+			case "-":
+				*target = ""
+			default:
+				color, ok := ansiColor(value)
+				if !ok {
+					valid = false
+				}
+				*target = color
+			}
+		}
+		switch match[3] {
+		case "":
+		case "-":
+			newStyle.attrs = ""
+		default:
+			newStyle.attrs = match[3]
+		}
+		if !valid {
+			// Not a style tag, e.g. "[Done]": shown as is.
+			builder.WriteByte('[')
+			i++
+			continue
+		}
+		if newStyle != style {
+			style = newStyle
+			builder.WriteString(style.sequence())
+		}
+		i += len(match[0])
+	}
+	builder.WriteString("\x1b[0m")
+	return builder.String()
+}
+
+// renderFetch builds the fastfetch-style summary: the logo with, on its
+// right, the information modules and the usage bars.
+func renderFetch(theme *Theme, staticInfo *StaticInfo, sample *Sample) string {
+	titleColor := fmt.Sprintf("[%s]", theme.InfoPanel.TitleColor.TrueColor().String())
+	var right []string
+	if staticInfo.User != "" {
+		header := staticInfo.User + "@" + staticInfo.Hostname
+		right = append(right, fmt.Sprintf("%s::b]%s[-::-]", strings.TrimSuffix(titleColor, "]"), tview.Escape(header)), strings.Repeat("-", tview.TaggedStringWidth(tview.Escape(header))))
+	}
+	for _, line := range infoLines(staticInfo, sample, userPrefs.Info) {
+		right = append(right, fmt.Sprintf("%s%s:[-] %s", titleColor, tview.Escape(line.Label), tview.Escape(line.Value)))
+	}
+	right = append(right, "")
+	labelWidth := 0
+	for _, label := range []string{"CPU", "Memory", "Swap", "Disk"} {
+		labelWidth = max(labelWidth, tview.TaggedStringWidth(tview.Escape(tr(label))))
+	}
+	usage := func(label string, percent float64, detail string) {
+		bar, colorCode := createBar(theme, percent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, fitBarWidth(0, 0))
+		right = append(right, fmt.Sprintf("%s%s[-] %s %s%5s%%[-] %s", titleColor, padRight(tview.Escape(tr(label)), labelWidth), bar, colorCode, formatNumber(percent, 1), detail))
+	}
+	cpuPercent, _ := effectiveCPU(sample)
+	usage("CPU", cpuPercent, "")
+	total, used, memPercent, _ := effectiveMemory(sample)
+	usage("Memory", memPercent, fmt.Sprintf("(%s/%s)", formatBytes(used), formatBytes(total)))
+	if sample.SwapTotal > 0 {
+		usage("Swap", sample.SwapUsedPercent, fmt.Sprintf("(%s/%s)", formatBytes(sample.SwapUsed), formatBytes(sample.SwapTotal)))
+	}
+	for _, disk := range sample.Disks {
+		usage("Disk", disk.UsedPercent, tview.Escape(fmt.Sprintf("%s (%s/%s)", disk.Mountpoint, formatBytes(disk.Used), formatBytes(disk.Total))))
+	}
+
+	left := strings.Split(strings.TrimRight(staticInfo.Logo, "\n"), "\n")
+	logoWidth := 0
+	for _, line := range left {
+		if width := tview.TaggedStringWidth(line); width > logoWidth {
+			logoWidth = width
+		}
+	}
+	lines := len(left)
+	if len(right) > lines {
+		lines = len(right)
+	}
+	var text string
+	for i := 0; i < lines; i++ {
+		var logoLine, info string
+		if i < len(left) {
+			logoLine = left[i]
+		}
+		if i < len(right) {
+			info = right[i]
+		}
+		padding := strings.Repeat(" ", logoWidth-tview.TaggedStringWidth(logoLine)+3)
+		text += strings.TrimRight(logoLine+"[-:-:-]"+padding+info, " ") + "\n"
+	}
+	return tviewToANSI(text)
+}
+
+// fetchSample takes a sample for --fetch. The CPU usage is measured between
+// two collections, a quarter of a second apart.
+func fetchSample(source sampleSource) (*Sample, error) {
+	if _, ok := source.(*collector); ok {
+		source.Collect()
+		time.Sleep(250 * time.Millisecond)
+		return source.Collect()
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		sample, err := source.Collect()
+		if sample != nil || time.Now().After(deadline) {
+			return sample, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
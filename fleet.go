@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// FleetSettings is the [Fleet] table of the config file, listing the
+// TermiDash agents shown on the fleet page:
+//
+//	[Fleet]
+//	StaleAfter = "15s"
+//	[[Fleet.Hosts]]
+//	Name = "build"
+//	Address = "build.lan:9977"
+//
+// A host whose last sample is older than StaleAfter is marked as stale.
+type FleetSettings struct {
+	StaleAfter time.Duration `toml:"StaleAfter"`
+	Hosts      []FleetHost   `toml:"Hosts,omitempty"`
+}
+
+type FleetHost struct {
+	Name    string `toml:"Name"`
+	Address string `toml:"Address"`
+}
+
+var defaultFleetSettings = FleetSettings{
+	StaleAfter: 15 * time.Second,
+}
+
+// fleetMember is one row of the fleet page. The machine of the current
+// dashboard has no remote source: its samples come from the main loop.
+type fleetMember struct {
+	name    string
+	address string
+	remote  *remoteSource
+	alerts  *alertEngine
+
+	lastSample *Sample
+}
+
+// fleet is the fleet page: a table with one row per machine.
+type fleet struct {
+	app        *tview.Application
+	table      *tview.Table
+	members    []*fleetMember
+	staleAfter time.Duration
+}
+
+func newFleet(app *tview.Application, settings FleetSettings, localName string) *fleet {
+	f := &fleet{app: app, staleAfter: settings.StaleAfter}
+	f.members = append(f.members, &fleetMember{name: localName + " (" + tr("current") + ")"})
+	for _, host := range settings.Hosts {
+		name := host.Name
+		if name == "" {
+			name = host.Address
+		}
+		f.members = append(f.members, &fleetMember{
+			name:    name,
+			address: host.Address,
+			remote:  newRemoteSource(host.Address),
+			alerts:  newAlertEngine(userPrefs.Alerts),
+		})
+	}
+	f.table = tview.NewTable()
+	f.table.SetBorder(true)
+	f.table.SetTitle(tr("Fleet - Enter to open a host, ESC or 'f' to go back"))
+	f.table.SetFixed(1, 0)
+	f.table.SetSelectable(true, false)
+	return f
+}
+
+// open shows the full dashboard of the host on the given row. Remote hosts
+// are opened in a child TermiDash connected to their agent, the fleet page
+// comes back when it is quit.
+func (f *fleet) open(row int, showLocal func()) {
+	if row < 1 || row > len(f.members) {
+		return
+	}
+	member := f.members[row-1]
+	if member.remote == nil {
+		showLocal()
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+	f.app.Suspend(func() {
+		cmd := fleetMemberCommand(executable, member.address)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "TermiDash: %s: %v\nPress Enter to go back.", member.name, err)
+			fmt.Scanln()
+		}
+	})
+}
+
+// fleetMemberCommand runs the TermiDash showing the dashboard of the agent at
+// address, as a fleet member: see configExtras.
+func fleetMemberCommand(executable, address string) *exec.Cmd {
+	return exec.Command(executable, "--fleet-member", "--connect", address)
+}
+
+var fleetColumns = []string{"Host", "State", "CPU", "Memory", "Worst disk", "Max temp", "Alerts"}
+
+// Update refreshes every row, the local one from the given sample. The
+// remote hosts' alert rules are evaluated here, but their actions only run
+// in the TermiDash showing their full dashboard.
+func (f *fleet) Update(theme *Theme, local *Sample, localAlerts *alertEngine, now time.Time) {
+	var rows [][]string
+	for _, member := range f.members {
+		sample, state := local, fmt.Sprintf("[%s]%s[-]", theme.BarGreen.TrueColor().String(), tr("live"))
+		alerts := localAlerts
+		if member.remote != nil {
+			var err error
+			var received time.Time
+			sample, received, err = member.remote.Latest()
+			alerts = member.alerts
+			switch {
+			case err != nil && sample == nil:
+				state = fmt.Sprintf("[%s]%s[-]", theme.BarRed.TrueColor().String(), tr("down"))
+			case err != nil:
+				state = fmt.Sprintf("[%s]"+tr("down, stale %s")+"[-]", theme.BarRed.TrueColor().String(), now.Sub(received).Round(time.Second))
+			case sample == nil:
+				state = tr("connecting")
+			case now.Sub(received) > f.staleAfter:
+				state = fmt.Sprintf("[%s]"+tr("stale %s")+"[-]", theme.BarYellow.TrueColor().String(), now.Sub(received).Round(time.Second))
+			}
+			if sample != nil && sample != member.lastSample {
+				member.alerts.Evaluate(sample)
+				member.lastSample = sample
+			}
+		}
+		rows = append(rows, fleetRow(theme, member.name, state, sample, alerts))
+	}
+	f.app.QueueUpdateDraw(func() {
+		for column, name := range fleetColumns {
+			f.table.SetCell(0, column, tview.NewTableCell(tr(name)).
+				SetTextColor(theme.InfoPanel.TitleColor).
+				SetBackgroundColor(theme.InfoPanel.BackGroundColor).
+				SetAttributes(tcell.AttrBold).
+				SetSelectable(false))
+		}
+		for i, cells := range rows {
+			for column, cell := range cells {
+				f.table.SetCell(i+1, column, tview.NewTableCell(cell).
+					SetExpansion(1).
+					SetTextColor(theme.InfoPanel.TextColor).
+					SetBackgroundColor(theme.InfoPanel.BackGroundColor))
+			}
+		}
+	})
+}
+
+func fleetRow(theme *Theme, name, state string, sample *Sample, alerts *alertEngine) []string {
+	cells := []string{tview.Escape(name), state, "-", "-", "-", "-", "-"}
+	if sample == nil {
+		return cells
+	}
+	cpuPercent, _ := effectiveCPU(sample)
+	cpuColor := barColor(theme, cpuPercent)
+	cells[2] = fmt.Sprintf("%s%s%%[-]", cpuColor, formatNumber(cpuPercent, 1))
+	_, _, memPercent, _ := effectiveMemory(sample)
+	memColor := barColor(theme, memPercent)
+	cells[3] = fmt.Sprintf("%s%s%%[-]", memColor, formatNumber(memPercent, 1))
+	var worst *DiskSample
+	for i := range sample.Disks {
+		if worst == nil || sample.Disks[i].UsedPercent > worst.UsedPercent {
+			worst = &sample.Disks[i]
+		}
+	}
+	if worst != nil {
+		diskColor := barColor(theme, worst.UsedPercent)
+		cells[4] = fmt.Sprintf("%s %s%s%%[-]", tview.Escape(worst.Mountpoint), diskColor, formatNumber(worst.UsedPercent, 1))
+	}
+	var hottest *TempSample
+	for i := range sample.Temps {
+		if hottest == nil || sample.Temps[i].Temperature > hottest.Temperature {
+			hottest = &sample.Temps[i]
+		}
+	}
+	if hottest != nil {
+		tempColor := barColor(theme, tempPercent(*hottest))
+		cells[5] = fmt.Sprintf("%s%s[-]", tempColor, formatTemp(hottest.Temperature))
+	}
+	if firing := alerts.ActiveCount(); firing > 0 {
+		cells[6] = fmt.Sprintf("[%s]"+tr("%d firing")+"[-]", theme.BarRed.TrueColor().String(), firing)
+	} else {
+		cells[6] = fmt.Sprintf("[%s]%s[-]", theme.BarGreen.TrueColor().String(), tr("ok"))
+	}
+	return cells
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestFleetMemberCommand(t *testing.T) {
+	cmd := fleetMemberCommand("/usr/bin/termidash", "build.lan:9977")
+	if want := []string{"/usr/bin/termidash", "--fleet-member", "--connect", "build.lan:9977"}; !slices.Equal(cmd.Args, want) {
+		t.Errorf("got %q, want %q", cmd.Args, want)
+	}
+}
+
+func TestFleetMemberExtras(t *testing.T) {
+	useDefaultPreferences(t)
+	userPrefs.Fleet.Hosts = []FleetHost{{Name: "build", Address: "127.0.0.1:1"}}
+	userPrefs.Panels = []CustomPanel{{Title: "queue", Command: "echo 3", Tab: "CI"}}
+	userPrefs.Tails = []LogTailSettings{{Title: "syslog", Files: []string{"/var/log/syslog"}, Tab: "Logs"}}
+
+	extras := configExtras(userPrefs, false)
+	if len(extras.Fleet.Hosts) != 1 || len(extras.Panels) != 1 || len(extras.Tails) != 1 {
+		t.Errorf("the dashboard's extras are %+v, want those of the config", extras)
+	}
+
+	// The application isn't run: a member starts nothing that draws on it.
+	staticInfo := fakeStaticInfo()
+	d := newDashboard(tview.NewApplication(), &staticInfo, newRefreshControl(time.Second), newAlertEngine(nil), configExtras(userPrefs, true))
+	if len(d.fleet.members) != 1 || d.fleet.members[0].remote != nil {
+		t.Errorf("the member's fleet has %d hosts, want only the one it shows", len(d.fleet.members))
+	}
+	if !slices.Equal(d.tabs.names, builtinTabs) || len(d.panelTabs) != 0 {
+		t.Errorf("the member has the tabs %q, want only the built-in ones", d.tabs.names)
+	}
+	if len(d.tails) != 0 {
+		t.Errorf("the member tails %d files, want none", len(d.tails))
+	}
+	// The config saved from the member's settings keeps everything.
+	if len(userPrefs.Fleet.Hosts) != 1 || len(userPrefs.Panels) != 1 || len(userPrefs.Tails) != 1 {
+		t.Errorf("the preferences lost their extras: %+v", userPrefs)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatSettings is the [Format] table of the config file, choosing how the
+// numbers, sizes, temperatures and dates are shown in every panel:
+//
+//	[Format]
+//	Units = "iec"
+//	Precision = 2
+//	Temperature = "C"
+//	Clock = "24h"
+//	DateFormat = ""
+//	DecimalSeparator = ""
+//
+// Units is "iec" (KiB, MiB... powers of 1024) or "si" (kB, MB... powers of
+// 1000). Temperature is "C", "F" or "K". Clock is "24h" or "12h". DateFormat,
+// when set, is a Go time layout used for every date instead of the default
+// one and Clock, e.g. "02/01/2006 15:04". DecimalSeparator defaults to the
+// one of the locale (LC_ALL, LC_NUMERIC or LANG).
+type FormatSettings struct {
+	Units            string `toml:"Units"`
+	Precision        int    `toml:"Precision"`
+	Temperature      string `toml:"Temperature"`
+	Clock            string `toml:"Clock"`
+	DateFormat       string `toml:"DateFormat,omitempty"`
+	DecimalSeparator string `toml:"DecimalSeparator,omitempty"`
+}
+
+var defaultFormatSettings = FormatSettings{
+	Units:       "iec",
+	Precision:   2,
+	Temperature: "C",
+	Clock:       "24h",
+}
+
+// commaLanguages are the languages writing decimals with a comma.
+var commaLanguages = map[string]bool{
+	"bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true,
+	"es": true, "et": true, "fi": true, "fr": true, "hr": true, "hu": true,
+	"id": true, "is": true, "it": true, "lt": true, "lv": true, "nb": true,
+	"nl": true, "nn": true, "no": true, "pl": true, "pt": true, "ro": true,
+	"ru": true, "sk": true, "sl": true, "sr": true, "sv": true, "tr": true,
+	"uk": true, "vi": true,
+}
+
+// localeSeparator is the decimal separator of the locale, e.g. "," for
+// "de_DE.UTF-8".
+var localeSeparator = sync.OnceValue(func() string {
+	if commaLanguages[localeLanguage("LC_ALL", "LC_NUMERIC", "LANG")] {
+		return ","
+	}
+	return "."
+})
+
+// formatNumber writes value with the given number of decimals and the
+// chosen decimal separator.
+func formatNumber(value float64, decimals int) string {
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	separator := userPrefs.Format.DecimalSeparator
+	if separator == "" {
+		separator = localeSeparator()
+	}
+	if separator != "." {
+		text = strings.Replace(text, ".", separator, 1)
+	}
+	return text
+}
+
+// formatFloat writes value with the chosen precision.
+func formatFloat(value float64) string {
+	return formatNumber(value, max(userPrefs.Format.Precision, 0))
+}
+
+// formatPercent writes a percentage with the chosen precision, e.g. "42.50%".
+func formatPercent(value float64) string {
+	return formatFloat(value) + "%"
+}
+
+// formatTemp converts a temperature in Celsius to the chosen unit, e.g.
+// "108.50F".
+func formatTemp(celsius float64) string {
+	switch strings.ToUpper(userPrefs.Format.Temperature) {
+	case "F":
+		return formatFloat(celsius*9/5+32) + "F"
+	case "K":
+		return formatFloat(celsius+273.15) + "K"
+	}
+	return formatFloat(celsius) + "C"
+}
+
+// formatTime writes a date and time with DateFormat, or the default layout
+// with the chosen clock. Without seconds, they are left out of the default
+// layout.
+func formatTime(t time.Time, seconds bool) string {
+	if userPrefs.Format.DateFormat != "" {
+		return t.Format(userPrefs.Format.DateFormat)
+	}
+	layout := "2006-01-02 15:04"
+	if strings.EqualFold(userPrefs.Format.Clock, "12h") {
+		layout = "2006-01-02 03:04"
+	}
+	if seconds {
+		layout += ":05"
+	}
+	if strings.EqualFold(userPrefs.Format.Clock, "12h") {
+		layout += " PM"
+	}
+	return t.Format(layout)
+}
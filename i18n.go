@@ -0,0 +1,72 @@
+package main
+
+import (
+	"embed"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rivo/tview"
+)
+
+// localeFiles are the translations of the interface, one <language>.toml
+// file per language. Each maps an English text to its translation:
+//
+//	"Total Memory: %s" = "Mémoire totale : %s"
+//
+// The English texts are the keys, so a text missing from a catalog is shown
+// in English.
+//
+//go:embed locales
+var localeFiles embed.FS
+
+// messages is the catalog of the selected language, nil for English.
+var messages map[string]string
+
+// localeLanguage is the language of the first locale variable set, e.g. "de"
+// for LANG=de_DE.UTF-8.
+func localeLanguage(names ...string) string {
+	for _, name := range names {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		language, _, _ := strings.Cut(locale, "_")
+		language, _, _ = strings.Cut(language, ".")
+		return strings.ToLower(language)
+	}
+	return ""
+}
+
+// loadLanguage selects the catalog of the language, or of the locale
+// (LC_ALL, LC_MESSAGES or LANG) when it is empty. Languages without a
+// catalog fall back to English.
+func loadLanguage(language string) {
+	if language == "" {
+		language = localeLanguage("LC_ALL", "LC_MESSAGES", "LANG")
+	}
+	messages = nil
+	data, err := localeFiles.ReadFile("locales/" + strings.ToLower(language) + ".toml")
+	if err != nil {
+		return
+	}
+	var catalog map[string]string
+	if _, err := toml.Decode(string(data), &catalog); err == nil {
+		messages = catalog
+	}
+}
+
+// tr translates an English text of the interface. Format strings are
+// translated before being filled, e.g. fmt.Sprintf(tr("%d alerts"), n).
+func tr(text string) string {
+	if translation, ok := messages[text]; ok && translation != "" {
+		return translation
+	}
+	return text
+}
+
+// padRight pads text (which may contain color tags and wide characters)
+// with spaces up to width columns.
+func padRight(text string, width int) string {
+	return text + strings.Repeat(" ", max(width-tview.TaggedStringWidth(text), 0))
+}
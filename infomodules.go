@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// InfoSettings is the [Info] table of the config file. It chooses which
+// lines the System Information panel shows, and in which order:
+//
+//	[Info]
+//	Icon = "❄"
+//	[[Info.Modules]]
+//	Type = "os"
+//	[[Info.Modules]]
+//	Type = "packages"
+//	Label = "Pkgs"
+//	Icon = "📦"
+//
+// Icon is used for every module that doesn't set its own, set it to "" for
+// no icon at all.
+type InfoSettings struct {
+	Icon    string       `toml:"Icon"`
+	Modules []InfoModule `toml:"Modules"`
+}
+
+// InfoModule is one line of the System Information panel. Label replaces the
+// module's default label.
+type InfoModule struct {
+	Type  string `toml:"Type"`
+	Label string `toml:"Label,omitempty"`
+	Icon  string `toml:"Icon,omitempty"`
+}
+
+var defaultInfoSettings = InfoSettings{
+	Icon: "❄",
+	Modules: []InfoModule{
+		{Type: "os"},
+		{Type: "family"},
+		{Type: "version"},
+		{Type: "kernel"},
+		{Type: "hostname"},
+		{Type: "uptime"},
+		{Type: "cpu"},
+	},
+}
+
+// infoModuleLabels are the available modules and their default labels.
+var infoModuleLabels = map[string]string{
+	"os":       "OS",
+	"family":   "OS family",
+	"version":  "OS version",
+	"kernel":   "Kernel Version",
+	"hostname": "Hostname",
+	"userhost": "User",
+	"uptime":   "Uptime",
+	"cpu":      "CPU Model",
+	"shell":    "Shell",
+	"terminal": "Terminal",
+	"de":       "DE/WM",
+	"packages": "Packages",
+	"init":     "Init",
+	"locale":   "Locale",
+	"memory":   "Memory",
+	"disk":     "Disk (/)",
+}
+
+// infoLine is one line of the System Information panel: the icon and label,
+// and the value.
+type infoLine struct {
+	Label string
+	Value string
+}
+
+// infoLines builds the lines of the chosen modules. Modules without a value
+// on this system (e.g. no desktop environment on a server) are left out.
+func infoLines(staticInfo *StaticInfo, sample *Sample, settings InfoSettings) []infoLine {
+	var lines []infoLine
+	for _, module := range settings.Modules {
+		label, known := infoModuleLabels[module.Type]
+		value := infoModuleValue(staticInfo, sample, module.Type)
+		if !known {
+			value = fmt.Sprintf(tr("unknown module %q"), module.Type)
+		}
+		if value == "" {
+			continue
+		}
+		if module.Label != "" {
+			label = module.Label
+		} else {
+			label = tr(label)
+		}
+		icon := settings.Icon
+		if module.Icon != "" {
+			icon = module.Icon
+		}
+		if icon != "" {
+			icon += " "
+		}
+		lines = append(lines, infoLine{Label: icon + label, Value: value})
+	}
+	return lines
+}
+
+// renderInfo builds the System Information panel: the logo followed by the
+// module lines.
+func renderInfo(staticInfo *StaticInfo, sample *Sample, settings InfoSettings) string {
+	text := staticInfo.Logo
+	for _, line := range infoLines(staticInfo, sample, settings) {
+		text += fmt.Sprintf("%s: %s\n", line.Label, line.Value)
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+func infoModuleValue(staticInfo *StaticInfo, sample *Sample, moduleType string) string {
+	if reason := infoModuleUnavailable(staticInfo, sample, moduleType); reason != "" {
+		return fmt.Sprintf(tr("unavailable: %s"), reason)
+	}
+	switch moduleType {
+	case "os":
+		return staticInfo.OS + " " + staticInfo.KernelArch
+	case "family":
+		return staticInfo.OSFamily
+	case "version":
+		return staticInfo.OSVersion
+	case "kernel":
+		return staticInfo.KernelVersion
+	case "hostname":
+		return staticInfo.Hostname
+	case "userhost":
+		if staticInfo.User == "" {
+			return ""
+		}
+		return staticInfo.User + "@" + staticInfo.Hostname
+	case "uptime":
+		return (time.Duration(sample.Uptime) * time.Second).String()
+	case "cpu":
+		return staticInfo.CPUModel
+	case "shell":
+		return staticInfo.Shell
+	case "terminal":
+		return staticInfo.Terminal
+	case "de":
+		return staticInfo.Desktop
+	case "packages":
+		return staticInfo.Packages
+	case "init":
+		return staticInfo.InitSystem
+	case "locale":
+		return staticInfo.Locale
+	case "memory":
+		total, used, percent, _ := effectiveMemory(sample)
+		if total == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s / %s (%s%%)", formatBytes(used), formatBytes(total), formatNumber(percent, 0))
+	case "disk":
+		for _, usage := range sample.Disks {
+			if usage.Mountpoint == "/" || strings.EqualFold(usage.Mountpoint, `C:\`) {
+				return fmt.Sprintf("%s / %s (%s%%)", formatBytes(usage.Used), formatBytes(usage.Total), formatNumber(usage.UsedPercent, 0))
+			}
+		}
+	}
+	return ""
+}
+
+// infoModuleUnavailable is why the probe giving the value of a module
+// failed, or "".
+func infoModuleUnavailable(staticInfo *StaticInfo, sample *Sample, moduleType string) string {
+	switch moduleType {
+	case "os", "family", "version":
+		return staticInfo.Unavailable["platform"]
+	case "kernel":
+		return staticInfo.Unavailable["kernel"]
+	case "hostname", "userhost":
+		return staticInfo.Unavailable["host"]
+	case "cpu":
+		return staticInfo.Unavailable["cpu"]
+	case "uptime", "memory":
+		return sample.Unavailable[moduleType]
+	case "disk":
+		return sample.Unavailable["disks"]
+	}
+	return ""
+}
+
+// detectEnvironment fills the parts of the static info that describe the
+// user's session rather than the machine: shell, terminal, desktop, package
+// counts, init system and locale.
+func detectEnvironment(staticInfo *StaticInfo) {
+	if current, err := user.Current(); err == nil {
+		staticInfo.User = current.Username
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		staticInfo.Shell = filepath.Base(shell)
+	} else if os.Getenv("PSModulePath") != "" {
+		staticInfo.Shell = "PowerShell"
+	}
+	switch {
+	case os.Getenv("TERM_PROGRAM") != "":
+		staticInfo.Terminal = os.Getenv("TERM_PROGRAM")
+	case os.Getenv("WT_SESSION") != "":
+		staticInfo.Terminal = "Windows Terminal"
+	default:
+		staticInfo.Terminal = os.Getenv("TERM")
+	}
+	desktop := os.Getenv("XDG_CURRENT_DESKTOP")
+	if desktop == "" {
+		desktop = os.Getenv("DESKTOP_SESSION")
+	}
+	if desktop != "" && os.Getenv("XDG_SESSION_TYPE") != "" {
+		desktop += " (" + os.Getenv("XDG_SESSION_TYPE") + ")"
+	}
+	staticInfo.Desktop = desktop
+	staticInfo.Packages = countPackages("/")
+	if comm, err := os.ReadFile("/proc/1/comm"); err == nil {
+		staticInfo.InitSystem = strings.TrimSpace(string(comm))
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			staticInfo.Locale = locale
+			break
+		}
+	}
+}
+
+// countPackages counts the installed packages of every package manager found
+// under root, e.g. "1843 (dpkg), 12 (apk)", from their local databases. rpm
+// is counted from its SQLite database, the default since rpm 4.16, and its
+// write-ahead log: the older Berkeley DB and ndb databases are not read.
+func countPackages(root string) string {
+	var counts []string
+	add := func(manager string, count int) {
+		if count > 0 {
+			counts = append(counts, fmt.Sprintf("%d (%s)", count, manager))
+		}
+	}
+	add("dpkg", countLines(filepath.Join(root, "var/lib/dpkg/status"), func(line string) bool {
+		return line == "Status: install ok installed"
+	}))
+	if entries, err := os.ReadDir(filepath.Join(root, "var/lib/pacman/local")); err == nil {
+		count := 0
+		for _, entry := range entries {
+			if entry.IsDir() {
+				count++
+			}
+		}
+		add("pacman", count)
+	}
+	add("rpm", countRPMPackages(root))
+	add("apk", countLines(filepath.Join(root, "lib/apk/db/installed"), func(line string) bool {
+		return strings.HasPrefix(line, "P:")
+	}))
+	return strings.Join(counts, ", ")
+}
+
+// countRPMPackages counts the rows of the Packages table of the rpm
+// database, which moved to /usr/lib/sysimage/rpm in recent distributions.
+// It returns 0 when there is none or it can't be read.
+func countRPMPackages(root string) int {
+	for _, dir := range []string{"var/lib/rpm", "usr/lib/sysimage/rpm"} {
+		if count, err := countSQLiteRows(filepath.Join(root, dir, "rpmdb.sqlite"), "Packages"); err == nil {
+			return count
+		}
+	}
+	return 0
+}
+
+// countLines counts the lines of a file that match, 0 when it can't be read.
+func countLines(file string, match func(string) bool) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if match(scanner.Text()) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCountPackages(t *testing.T) {
+	for _, test := range []struct {
+		root string
+		want string
+	}{
+		{"debian", "3 (dpkg)"},
+		{"arch", "3 (pacman)"},
+		{"alpine", "2 (apk)"},
+		{"mixed", "3 (dpkg), 2 (apk)"},
+		// rpm 4.16 and newer keep the database in /var/lib/rpm, moved to
+		// /usr/lib/sysimage/rpm by recent distributions.
+		{"rhel", "3 (rpm)"},
+		{"fedora", "150 (rpm)"},
+		// Right after a dnf transaction, part of it is in the log.
+		{"fedora-dnf", "60 (rpm)"},
+		{"broken-rpm", ""},
+		{"missing", ""},
+	} {
+		if got := countPackages(filepath.Join("testdata", "packages", test.root)); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.root, got, test.want)
+		}
+	}
+}
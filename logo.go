@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// logoAliases maps the names a system can be detected as to the logo file
+// drawn for it, for the logos that are not named after the os-release ID.
+var logoAliases = map[string]string{
+	"raspbian":   "raspberrypi",
+	"archarm":    "arch",
+	"archlinux":  "arch",
+	"mint":       "linuxmint",
+	"darwin":     "macos",
+	"kali-linux": "kali",
+}
+
+// readOSRelease parses an os-release file (KEY="value" lines) into a map.
+func readOSRelease(file string) map[string]string {
+	values := make(map[string]string)
+	f, err := os.Open(file)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// logoCandidates lists the logos to look for, best first: the one forced with
+// the Logo setting, the os-release ID and then each ID_LIKE entry (so Zorin
+// falls back to Ubuntu, then Debian), and finally what gopsutil reports,
+// which is all there is on Windows and macOS.
+func logoCandidates(forced string, osRelease map[string]string, platform, family, version string) []string {
+	var candidates []string
+	if forced != "" {
+		candidates = append(candidates, strings.ToLower(forced))
+	}
+	if id := osRelease["ID"]; id != "" {
+		candidates = append(candidates, strings.ToLower(id))
+	}
+	for _, like := range strings.Fields(osRelease["ID_LIKE"]) {
+		candidates = append(candidates, strings.ToLower(like))
+	}
+	switch {
+	case strings.Contains(platform, "Microsoft Windows 10"):
+		candidates = append(candidates, "windows10")
+	case strings.Contains(platform, "Microsoft Windows 11"):
+		candidates = append(candidates, "windows11")
+	case strings.Contains(platform, "macOS") || family == "Darwin":
+		candidates = append(candidates, "macos")
+	case strings.Contains(version, "kali"):
+		candidates = append(candidates, "kali")
+	}
+	candidates = append(candidates, strings.ToLower(platform))
+	for i, name := range candidates {
+		if alias, ok := logoAliases[name]; ok {
+			candidates[i] = alias
+		}
+	}
+	return append(candidates, "linux")
+}
+
+// loadLogo returns the first candidate logo found, translated to tview
+// colors. Each one is looked up in the user's logos directory first, then in
+// the embedded logos.
+func loadLogo(candidates []string, userDir string) string {
+	for _, name := range candidates {
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			continue
+		}
+		logoBytes, err := os.ReadFile(filepath.Join(userDir, name+".ascii"))
+		if err != nil {
+			logoBytes, err = logoFiles.ReadFile("logos/" + name + ".ascii")
+		}
+		if err == nil {
+			return tview.TranslateANSI(string(logoBytes)) + "\n"
+		}
+	}
+	return ""
+}
+
+// detectLogo finds the logo of the running system.
+func detectLogo(forced, platform, family, version string) string {
+	osRelease := readOSRelease("/etc/os-release")
+	if len(osRelease) == 0 {
+		osRelease = readOSRelease("/usr/lib/os-release")
+	}
+	configDir, _ := os.UserConfigDir()
+	userDir := filepath.Join(configDir, "TermiDash", "logos")
+	return loadLogo(logoCandidates(forced, osRelease, platform, family, version), userDir)
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// LogTailSettings is one [[Tails]] entry of the config file: a panel
+// following log files like `tail -F`.
+//
+//	[[Tails]]
+//	Title = "nginx"
+//	Files = ["/var/log/nginx/access.log", "/var/log/nginx/error.log"]
+//	Lines = 200
+//	Tab = "Logs"
+//	[[Tails.Highlights]]
+//	Pattern = "error|crit"
+//	Color = "red"
+//
+// Slot = "disk" shows the panel in place of the Disk Usage panel of the
+// Overview instead of on a tab. The "red", "yellow" and "green" highlight
+// colors are the ones of the theme, any other tview color can be used too.
+type LogTailSettings struct {
+	Title      string         `toml:"Title"`
+	Files      []string       `toml:"Files"`
+	Lines      int            `toml:"Lines,omitempty"`
+	Tab        string         `toml:"Tab,omitempty"`
+	Slot       string         `toml:"Slot,omitempty"`
+	Highlights []LogHighlight `toml:"Highlights,omitempty"`
+}
+
+type LogHighlight struct {
+	Pattern string `toml:"Pattern"`
+	Color   string `toml:"Color"`
+}
+
+// logTailPoll is how often the files are checked for new lines.
+const logTailPoll = 500 * time.Millisecond
+
+// tailLine is one line read from a file.
+type tailLine struct {
+	file string
+	text string
+}
+
+// tailHighlight is a compiled LogHighlight.
+type tailHighlight struct {
+	pattern *regexp.Regexp
+	color   string
+}
+
+// logTail follows the files of a LogTailSettings and shows their last lines.
+type logTail struct {
+	settings   LogTailSettings
+	view       *tview.TextView
+	tab        string
+	files      []*tailedFile
+	highlights []tailHighlight
+
+	// highlightErrors are the patterns that don't compile, shown with the
+	// errors of the files.
+	highlightErrors string
+
+	mu     sync.Mutex
+	lines  []tailLine
+	errors string
+	filter *regexp.Regexp
+}
+
+func newLogTail(settings LogTailSettings) *logTail {
+	if settings.Lines <= 0 {
+		settings.Lines = 200
+	}
+	if settings.Title == "" {
+		settings.Title = strings.Join(settings.Files, ", ")
+	}
+	tail := &logTail{settings: settings, tab: settings.Tab}
+	if tail.tab == "" {
+		tail.tab = "Logs"
+	}
+	if settings.Slot == "disk" {
+		tail.tab = "Overview"
+	}
+	for _, file := range settings.Files {
+		tail.files = append(tail.files, &tailedFile{path: file})
+	}
+	for _, highlight := range settings.Highlights {
+		pattern, err := regexp.Compile(highlight.Pattern)
+		if err != nil {
+			tail.highlightErrors += tr("invalid highlight: ") + err.Error() + "\n"
+			continue
+		}
+		tail.highlights = append(tail.highlights, tailHighlight{pattern, highlight.Color})
+	}
+	tail.view = newPanel(tview.Escape(settings.Title))
+	tail.view.SetScrollable(true)
+	return tail
+}
+
+// run checks the files for new lines until the program exits. Lines keep
+// being read while the refresh is paused, only the panel is frozen, and
+// drawn once the refresh resumes.
+func (tail *logTail) run(app *tview.Application, refresh *refreshControl) {
+	defer exitOnPanic()
+	dirty := false
+	for {
+		var newLines []tailLine
+		errors := tail.highlightErrors
+		for _, file := range tail.files {
+			lines, err := file.poll(tail.settings.Lines)
+			for _, line := range lines {
+				newLines = append(newLines, tailLine{file: filepath.Base(file.path), text: line})
+			}
+			if err != nil {
+				errors += err.Error() + "\n"
+			}
+		}
+		tail.mu.Lock()
+		tail.lines = append(tail.lines, newLines...)
+		if len(tail.lines) > tail.settings.Lines {
+			tail.lines = tail.lines[len(tail.lines)-tail.settings.Lines:]
+		}
+		dirty = dirty || len(newLines) > 0 || errors != tail.errors
+		tail.errors = errors
+		tail.mu.Unlock()
+		if _, paused := refresh.State(); !paused && dirty {
+			dirty = false
+			app.QueueUpdateDraw(func() {
+				tail.view.SetText(tail.render(currentTheme))
+				tail.view.ScrollToEnd()
+			})
+		}
+		time.Sleep(logTailPoll)
+	}
+}
+
+// SetFilter only shows the lines matching filter, or every line if it is nil.
+// It runs on the application's goroutine.
+func (tail *logTail) SetFilter(theme *Theme, filter *regexp.Regexp) {
+	tail.mu.Lock()
+	tail.filter = filter
+	tail.mu.Unlock()
+	title := tail.settings.Title
+	if filter != nil {
+		title += " - " + tr("filter:") + " " + strings.TrimPrefix(filter.String(), "(?i)")
+	}
+	tail.view.SetTitle(tview.Escape(title))
+	tail.view.SetText(tail.render(theme))
+	tail.view.ScrollToEnd()
+}
+
+// render builds the text of the panel: the lines matching the filter, with
+// the name of their file when there are several files, and the errors of
+// the files that can't be read.
+func (tail *logTail) render(theme *Theme) string {
+	tail.mu.Lock()
+	defer tail.mu.Unlock()
+	titleColor := fmt.Sprintf("[%s]", theme.InfoPanel.TitleColor.TrueColor().String())
+	var text string
+	for _, line := range tail.lines {
+		if tail.filter != nil && !tail.filter.MatchString(line.text) {
+			continue
+		}
+		if len(tail.files) > 1 {
+			text += titleColor + tview.Escape(line.file) + "[-] "
+		}
+		text += highlightLine(theme, line.text, tail.highlights) + "\n"
+	}
+	if tail.errors != "" {
+		text += fmt.Sprintf("[%s]%s[-]", theme.BarRed.TrueColor().String(), tview.Escape(tail.errors))
+	}
+	return text
+}
+
+// highlightLine colors the parts of the line matched by the highlights. When
+// two highlights match the same text, the first one wins.
+func highlightLine(theme *Theme, line string, highlights []tailHighlight) string {
+	colors := make([]string, len(line))
+	for i := len(highlights) - 1; i >= 0; i-- {
+		color := highlightColor(theme, highlights[i].color)
+		for _, match := range highlights[i].pattern.FindAllStringIndex(line, -1) {
+			for j := match[0]; j < match[1]; j++ {
+				colors[j] = color
+			}
+		}
+	}
+	var text string
+	for start := 0; start < len(line); {
+		end := start + 1
+		for end < len(line) && colors[end] == colors[start] {
+			end++
+		}
+		part := tview.Escape(line[start:end])
+		if colors[start] != "" {
+			part = "[" + colors[start] + "]" + part + "[-]"
+		}
+		text += part
+		start = end
+	}
+	return text
+}
+
+// highlightColor turns the name of a highlight color into a tview color,
+// using the theme's colors for red, yellow and green.
+func highlightColor(theme *Theme, name string) string {
+	switch strings.ToLower(name) {
+	case "red":
+		return theme.BarRed.TrueColor().String()
+	case "yellow":
+		return theme.BarYellow.TrueColor().String()
+	case "green", "":
+		return theme.BarGreen.TrueColor().String()
+	}
+	if tcell.GetColor(name) == tcell.ColorDefault {
+		return theme.BarGreen.TrueColor().String()
+	}
+	return name
+}
+
+// tailedFile is one followed file. It is reopened when the path points to a
+// new file (rotation by renaming) and read again from the start when it
+// shrinks (rotation by truncation).
+type tailedFile struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial string
+	opened  bool
+	// cut is set when reading started in the middle of a line, which is
+	// dropped.
+	cut bool
+}
+
+// poll returns the lines added since the last call. The first time, it
+// returns the last lines already in the file, up to maxLines.
+func (tailed *tailedFile) poll(maxLines int) ([]string, error) {
+	info, err := os.Stat(tailed.path)
+	if err != nil {
+		tailed.close()
+		return nil, fmt.Errorf("%s: %w", tailed.path, errorReason(err))
+	}
+	var lines []string
+	if tailed.file != nil && !os.SameFile(info, tailed.info) {
+		// Rotated: the end of the old file, then the new one from its start.
+		lines = tailed.read(maxLines)
+		tailed.close()
+	}
+	if tailed.file == nil {
+		file, err := os.Open(tailed.path)
+		if err != nil {
+			return lines, fmt.Errorf("%s: %w", tailed.path, errorReason(err))
+		}
+		tailed.file = file
+		tailed.info = info
+		tailed.offset = 0
+		tailed.partial = ""
+		if !tailed.opened {
+			// The first time, only the last lines already there are shown.
+			tailed.skipTo(info.Size(), maxLines)
+			tailed.opened = true
+		}
+	}
+	if info.Size() < tailed.offset {
+		tailed.file.Seek(0, io.SeekStart)
+		tailed.offset = 0
+		tailed.partial = ""
+		tailed.cut = false
+	}
+	return append(lines, tailed.read(maxLines)...), nil
+}
+
+// skipTo moves to the point of the file from which about maxLines lines are
+// left before size, to avoid reading a whole big log.
+func (tailed *tailedFile) skipTo(size int64, maxLines int) {
+	start := size - int64(maxLines)*512
+	if start <= 0 {
+		start = 0
+	}
+	tailed.file.Seek(start, io.SeekStart)
+	tailed.offset = start
+	tailed.partial = ""
+	tailed.cut = start > 0
+}
+
+// read returns the complete lines from the offset to the end of the file.
+func (tailed *tailedFile) read(maxLines int) []string {
+	if info, err := tailed.file.Stat(); err == nil && info.Size()-tailed.offset > int64(maxLines)*512 {
+		tailed.skipTo(info.Size(), maxLines)
+	}
+	data, err := io.ReadAll(tailed.file)
+	tailed.offset += int64(len(data))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(tailed.partial+string(data), "\n")
+	tailed.partial = lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	if tailed.cut && len(lines) > 0 {
+		lines = lines[1:]
+		tailed.cut = false
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+func (tailed *tailedFile) close() {
+	if tailed.file != nil {
+		tailed.file.Close()
+		tailed.file = nil
+	}
+}
+
+// errorReason drops the operation and path of a file error, which the
+// messages already show.
+func errorReason(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// addLogTails creates the log tail panels of the config file and starts
+// following their files. The tails with Slot = "disk" are stacked in place
+// of the Disk Usage panel.
+func (d *dashboard) addLogTails(settings []LogTailSettings) {
+	var diskSlot *tview.Flex
+	for _, tailSettings := range settings {
+		tail := newLogTail(tailSettings)
+		if tailSettings.Slot == "disk" {
+			if diskSlot == nil {
+				diskSlot = tview.NewFlex().SetDirection(tview.FlexRow)
+				d.mainGrid.RemoveItem(d.diskPanel)
+				d.mainGrid.AddItem(diskSlot, 2, 0, 1, 2, 0, 0, false)
+			}
+			diskSlot.AddItem(tail.view, 0, 1, false)
+		} else {
+			d.addToPanelTab(tail.tab, tail.view)
+		}
+		d.tails = append(d.tails, tail)
+		d.themed = append(d.themed, themedPanel{tail.view, func(theme *Theme) PanelStyle { return theme.InfoPanel }})
+		go tail.run(d.app, d.refresh)
+	}
+
+	d.tailFilter = tview.NewInputField()
+	d.tailFilter.SetLabel(tr("Filter (regexp, empty for none): "))
+	d.tailFilter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			d.applyTailFilter(d.tailFilter.GetText())
+		}
+		d.root.ResizeItem(d.tailFilter, 0, 0)
+		d.tabs.Switch(d.tabs.current)
+	})
+}
+
+// currentTails are the log tails shown on the current tab.
+func (d *dashboard) currentTails() []*logTail {
+	var tails []*logTail
+	for _, tail := range d.tails {
+		if tail.tab == d.tabs.Current() {
+			tails = append(tails, tail)
+		}
+	}
+	return tails
+}
+
+// openTailFilter shows the filter prompt, if the current tab has log tails.
+func (d *dashboard) openTailFilter() {
+	if len(d.currentTails()) == 0 {
+		return
+	}
+	d.root.ResizeItem(d.tailFilter, 1, 0)
+	d.app.SetFocus(d.tailFilter)
+}
+
+// applyTailFilter filters the log tails of the current tab. The filter is
+// case-insensitive.
+func (d *dashboard) applyTailFilter(text string) {
+	var filter *regexp.Regexp
+	if text != "" {
+		var err error
+		filter, err = regexp.Compile("(?i)" + text)
+		if err != nil {
+			d.status.SetMessage(tr("invalid filter: ") + err.Error())
+			d.status.Render(currentTheme)
+			return
+		}
+	}
+	for _, tail := range d.currentTails() {
+		tail.SetFilter(currentTheme, filter)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTails runs the dashboard with log tails of the given contents shown in
+// place of the Disk Usage panel, and returns their files.
+func startTails(t *testing.T, contents ...string) (*testDashboard, []string) {
+	t.Helper()
+	useDefaultPreferences(t)
+	var files []string
+	for i, content := range contents {
+		file := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+		userPrefs.Tails = append(userPrefs.Tails, LogTailSettings{Title: "tail " + string(rune('A'+i)), Files: []string{file}, Slot: "disk"})
+	}
+	return runDashboard(t, fakeStaticInfo(), &defaultTheme, 120, 40), files
+}
+
+// shows reports whether the screen holds the text. The screen is read on
+// the application's goroutine, as the tails keep drawing.
+func (td *testDashboard) shows(text string) bool {
+	var shown bool
+	td.app.QueueUpdate(func() {
+		shown = strings.Contains(screenDump(td.screen), text)
+	})
+	return shown
+}
+
+func TestLogTailsStackedInDiskSlot(t *testing.T) {
+	td, _ := startTails(t, "first line of A\n", "first line of B\n")
+	waitFor(t, "both tails", func() bool {
+		return td.shows("first line of A") && td.shows("first line of B")
+	})
+	if !td.shows("tail A") || !td.shows("tail B") {
+		t.Error("a tail has no title on the screen")
+	}
+}
+
+func TestLogTailDrawnAfterResume(t *testing.T) {
+	td, files := startTails(t, "before the pause\n")
+	waitFor(t, "the first line", func() bool { return td.shows("before the pause") })
+
+	td.refresh.TogglePause()
+	f, err := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("while paused\n")
+	f.Close()
+	time.Sleep(3 * logTailPoll)
+	if td.shows("while paused") {
+		t.Error("a line was drawn while paused")
+	}
+
+	td.refresh.TogglePause()
+	waitFor(t, "the line read while paused", func() bool { return td.shows("while paused") })
+}
+
+func TestLogTailInvalidHighlight(t *testing.T) {
+	useDefaultPreferences(t)
+	file := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(file, []byte("an error\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	userPrefs.Tails = []LogTailSettings{{
+		Files:      []string{file},
+		Slot:       "disk",
+		Highlights: []LogHighlight{{Pattern: "error|(crit", Color: "red"}, {Pattern: "warn", Color: "yellow"}},
+	}}
+	td := runDashboard(t, fakeStaticInfo(), &defaultTheme, 120, 40)
+	waitFor(t, "the error of the pattern", func() bool {
+		return td.shows("an error") && td.shows("invalid highlight: error parsing regexp: missing closing )")
+	})
+	if len(td.tails[0].highlights) != 1 {
+		t.Errorf("%d highlights, want the one that compiles", len(td.tails[0].highlights))
+	}
+}
+
+// appendTo adds text to the end of a file.
+func appendTo(t *testing.T, file, text string) {
+	t.Helper()
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkPoll polls the file and compares the lines it returns.
+func checkPoll(t *testing.T, tailed *tailedFile, maxLines int, want ...string) {
+	t.Helper()
+	lines, err := tailed.poll(maxLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestTailedFileAppend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, file, "first\r\nsecond\n")
+	tailed := &tailedFile{path: file}
+	defer tailed.close()
+	checkPoll(t, tailed, 200, "first", "second")
+	checkPoll(t, tailed, 200)
+	// A line is returned once complete.
+	appendTo(t, file, "thi")
+	checkPoll(t, tailed, 200)
+	appendTo(t, file, "rd\nfourth\n")
+	checkPoll(t, tailed, 200, "third", "fourth")
+}
+
+func TestTailedFileSkipsToTheEnd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	var content strings.Builder
+	for i := range 100 {
+		fmt.Fprintf(&content, "line %03d %s\n", i, strings.Repeat(".", 90))
+	}
+	appendTo(t, file, content.String())
+	tailed := &tailedFile{path: file}
+	defer tailed.close()
+	// Reading starts 2*512 bytes before the end, in line 89, which is
+	// dropped as it is cut.
+	lines, err := tailed.poll(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 10 || !strings.HasPrefix(lines[0], "line 090 ") || !strings.HasPrefix(lines[9], "line 099 ") {
+		t.Errorf("got %d lines, from %.8q to %.8q, want the 10 complete ones from line 090", len(lines), lines[0], lines[len(lines)-1])
+	}
+}
+
+func TestTailedFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.log")
+	appendTo(t, file, "before\n")
+	tailed := &tailedFile{path: file}
+	defer tailed.close()
+	checkPoll(t, tailed, 200, "before")
+
+	// Renamed: the end of the old file, then the new one from its start.
+	appendTo(t, file, "end of the old file\n")
+	if err := os.Rename(file, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, file, "new file\n")
+	checkPoll(t, tailed, 200, "end of the old file", "new file")
+
+	// Not created yet: an error, then the new file from its start.
+	if err := os.Rename(file, filepath.Join(dir, "app.log.2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tailed.poll(200); err == nil {
+		t.Error("no error for the missing file")
+	}
+	appendTo(t, file, "newer file\n")
+	checkPoll(t, tailed, 200, "newer file")
+
+	// Truncated: read again from the start.
+	if err := os.WriteFile(file, []byte("kept\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkPoll(t, tailed, 200, "kept")
+}
+
+func TestHighlightLine(t *testing.T) {
+	red, yellow, green := defaultTheme.BarRed.TrueColor().String(), defaultTheme.BarYellow.TrueColor().String(), defaultTheme.BarGreen.TrueColor().String()
+	highlights := func(patterns ...string) []tailHighlight {
+		var compiled []tailHighlight
+		for i := 0; i < len(patterns); i += 2 {
+			compiled = append(compiled, tailHighlight{regexp.MustCompile(patterns[i]), patterns[i+1]})
+		}
+		return compiled
+	}
+	for _, test := range []struct {
+		line       string
+		highlights []tailHighlight
+		want       string
+	}{
+		{"[cache] hit", nil, "[cache[] hit"},
+		{"disk error, disk error", highlights("error", "red"), "disk [" + red + "]error[-], disk [" + red + "]error[-]"},
+		// The first highlight wins over the later ones on the same text.
+		{"warning: error", highlights("error", "red", "warn|ror", "yellow"), "[" + yellow + "]warn[-]ing: [" + red + "]error[-]"},
+		{"warning: error", highlights("warn|ror", "Yellow", "error", "red"), "[" + yellow + "]warn[-]ing: [" + red + "]er[-][" + yellow + "]ror[-]"},
+		{"[E] boot", highlights(`\[E\]`, "blue"), "[blue][E[][-] boot"},
+		{"ok", highlights("ok", ""), "[" + green + "]ok[-]"},
+		{"ok", highlights("ok", "not a color"), "[" + green + "]ok[-]"},
+	} {
+		if got := highlightLine(&defaultTheme, test.line, test.highlights); got != test.want {
+			t.Errorf("%q: got  %q\nwant %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestLogTailLinesCap(t *testing.T) {
+	useDefaultPreferences(t)
+	file := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, file, "line 1\nline 2\nline 3\n")
+	userPrefs.Tails = []LogTailSettings{{Files: []string{file}, Lines: 3, Slot: "disk"}}
+	td := runDashboard(t, fakeStaticInfo(), &defaultTheme, 120, 40)
+	waitFor(t, "the first lines", func() bool { return td.shows("line 3") })
+	appendTo(t, file, "line 4\nline 5\n")
+	waitFor(t, "the new lines", func() bool { return td.shows("line 5") })
+	if td.shows("line 1") || td.shows("line 2") || !td.shows("line 3") {
+		t.Error("the panel doesn't keep the last 3 lines")
+	}
+}
+
+func TestLogTailFilter(t *testing.T) {
+	td, _ := startTails(t, "GET /index\nPOST /login\nget /about\n", "GET /other\n")
+	waitFor(t, "the lines", func() bool { return td.shows("POST /login") && td.shows("GET /other") })
+
+	td.app.QueueUpdateDraw(func() { td.applyTailFilter("^get") })
+	waitFor(t, "the filter", func() bool { return td.shows("tail A - filter: ^get") })
+	// Case-insensitive, on every tail of the tab.
+	if !td.shows("GET /index") || !td.shows("get /about") || !td.shows("GET /other") || td.shows("POST /login") {
+		t.Error("the filter doesn't show the lines starting with get")
+	}
+
+	// An invalid filter keeps the current one.
+	td.app.QueueUpdateDraw(func() { td.applyTailFilter("(get") })
+	waitFor(t, "the error", func() bool {
+		td.status.mu.Lock()
+		defer td.status.mu.Unlock()
+		return strings.HasPrefix(td.status.message, "invalid filter: ")
+	})
+	if td.shows("POST /login") {
+		t.Error("the invalid filter removed the current one")
+	}
+
+	td.app.QueueUpdateDraw(func() { td.applyTailFilter("") })
+	waitFor(t, "every line", func() bool { return td.shows("POST /login") && !td.shows("filter:") })
+}
@@ -2,28 +2,23 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/disk"
-	"github.com/shirou/gopsutil/v4/host"
-	"github.com/shirou/gopsutil/v4/mem"
-	"github.com/shirou/gopsutil/v4/sensors"
 )
 
 //go:embed logos
 var logoFiles embed.FS
 var currentTheme = &defaultTheme
 
-const barWidth = 20
-
 type PanelStyle struct {
 	BorderColor     tcell.Color
 	TitleColor      tcell.Color
@@ -32,15 +27,20 @@ type PanelStyle struct {
 }
 
 type Theme struct {
+	Name string
+
 	CPUPanel  PanelStyle
 	MemPanel  PanelStyle
 	InfoPanel PanelStyle
 	DiskPanel PanelStyle
 	TempPanel PanelStyle
+	ProcPanel PanelStyle
+	NetPanel  PanelStyle
 
 	BarRed                tcell.Color
 	BarYellow             tcell.Color
 	BarGreen              tcell.Color
+	StackColors           []tcell.Color
 	Backgroundcolor       tcell.Color
 	DropDownOptionStyle   tcell.Style
 	DropDownSelectedStyle tcell.Style
@@ -49,6 +49,24 @@ type UserPreferences struct {
 	BarFilledChar string `toml:"BarFilledChar"`
 	BarEmptyChar  string `toml:"BarEmptyChar"`
 	ThemeName     string `toml:"ThemeName"`
+	Logo          string `toml:"Logo,omitempty"`
+	Language      string `toml:"Language,omitempty"`
+	BarStyle      string `toml:"BarStyle,omitempty"`
+	BarGradient   bool   `toml:"BarGradient,omitempty"`
+	BarWidth      int    `toml:"BarWidth,omitempty"`
+
+	RefreshInterval time.Duration `toml:"RefreshInterval"`
+	Alerts          []AlertRule   `toml:"Alerts,omitempty"`
+
+	AlertActions AlertActionSettings `toml:"AlertActions"`
+	Sensors      SensorSettings      `toml:"Sensors"`
+	Disks        DiskSettings        `toml:"Disks"`
+	Info         InfoSettings        `toml:"Info"`
+	Fleet        FleetSettings       `toml:"Fleet"`
+	Log          LogSettings         `toml:"Log"`
+	Format       FormatSettings      `toml:"Format"`
+	Panels       []CustomPanel       `toml:"Panels,omitempty"`
+	Tails        []LogTailSettings   `toml:"Tails,omitempty"`
 }
 
 var userPrefs UserPreferences
@@ -64,15 +82,38 @@ type StaticInfo struct {
 	CPUPhysCore   int
 	CPULogCore    int
 	CPUModel      string
+
+	CPUVendor          string
+	CPUFamily          string
+	CPUMHz             float64
+	CPUCacheSize       int32
+	Virtualization     string
+	VirtualizationRole string
+	BootTime           uint64
+
+	User       string
+	Shell      string
+	Terminal   string
+	Desktop    string
+	Packages   string
+	InitSystem string
+	Locale     string
+
+	// Pressure is whether the kernel exposes PSI.
+	Pressure bool
+	// Unavailable is why each probe that failed read nothing.
+	Unavailable probeErrors
 }
 
 const defaultUserPreferencesTOML = `
 BarFilledChar = "❄"
 BarEmptyChar = "-"
 ThemeName = "Default"
+RefreshInterval = "1s"
 `
 
 var defaultTheme = Theme{
+	Name: "Default",
 	CPUPanel: PanelStyle{
 		BorderColor:     tcell.ColorGreen,
 		TitleColor:      tcell.ColorGreen,
@@ -103,14 +144,28 @@ var defaultTheme = Theme{
 		TextColor:       tcell.ColorWhite,
 		BackGroundColor: tcell.GetColor("#000000"),
 	},
+	ProcPanel: PanelStyle{
+		BorderColor:     tcell.ColorTeal,
+		TitleColor:      tcell.ColorTeal,
+		TextColor:       tcell.ColorWhite,
+		BackGroundColor: tcell.GetColor("#000000"),
+	},
+	NetPanel: PanelStyle{
+		BorderColor:     tcell.ColorYellow,
+		TitleColor:      tcell.ColorYellow,
+		TextColor:       tcell.ColorWhite,
+		BackGroundColor: tcell.GetColor("#000000"),
+	},
 	BarGreen:              tcell.ColorGreen,
 	BarYellow:             tcell.ColorYellow,
 	BarRed:                tcell.ColorRed,
+	StackColors:           []tcell.Color{tcell.ColorRed, tcell.ColorBlue, tcell.ColorYellow, tcell.ColorFuchsia, tcell.ColorAqua, tcell.ColorOrange, tcell.ColorGreen},
 	Backgroundcolor:       tcell.GetColor("#000000"),
 	DropDownOptionStyle:   tcell.StyleDefault.Foreground(tcell.GetColor("#ffffff")).Background(tcell.GetColor("#000000")),
 	DropDownSelectedStyle: tcell.StyleDefault.Foreground(tcell.GetColor("#000000")).Background(tcell.ColorLightGray),
 }
 var nordTheme = Theme{
+	Name: "Nord",
 	CPUPanel: PanelStyle{
 		BorderColor:     tcell.GetColor("#3b4252"),
 		TitleColor:      tcell.GetColor("#88c0d0"),
@@ -141,15 +196,29 @@ var nordTheme = Theme{
 		TextColor:       tcell.GetColor("#eceff4"),
 		BackGroundColor: tcell.GetColor("#2e3440"),
 	},
+	ProcPanel: PanelStyle{
+		BorderColor:     tcell.GetColor("#3b4252"),
+		TitleColor:      tcell.GetColor("#a3be8c"),
+		TextColor:       tcell.GetColor("#eceff4"),
+		BackGroundColor: tcell.GetColor("#2e3440"),
+	},
+	NetPanel: PanelStyle{
+		BorderColor:     tcell.GetColor("#3b4252"),
+		TitleColor:      tcell.GetColor("#ebcb8b"),
+		TextColor:       tcell.GetColor("#eceff4"),
+		BackGroundColor: tcell.GetColor("#2e3440"),
+	},
 	BarGreen:              tcell.GetColor("#a3be8c"),
 	BarYellow:             tcell.GetColor("#ebcb8b"),
 	BarRed:                tcell.GetColor("#bf616a"),
+	StackColors:           []tcell.Color{tcell.GetColor("#bf616a"), tcell.GetColor("#5e81ac"), tcell.GetColor("#ebcb8b"), tcell.GetColor("#b48ead"), tcell.GetColor("#88c0d0"), tcell.GetColor("#d08770"), tcell.GetColor("#a3be8c")},
 	Backgroundcolor:       tcell.GetColor("#2E3440"),
 	DropDownOptionStyle:   tcell.StyleDefault.Foreground(tcell.GetColor("#eceff4")).Background(tcell.GetColor("#434c5e")),
 	DropDownSelectedStyle: tcell.StyleDefault.Foreground(tcell.GetColor("#2e3440")).Background(tcell.GetColor("#88c0d0")),
 }
 
 var snowTheme = Theme{
+	Name: "Snow Day",
 	CPUPanel: PanelStyle{
 		BorderColor:     tcell.GetColor("#d8dee9"),
 		TitleColor:      tcell.GetColor("#5e81ac"),
@@ -180,15 +249,39 @@ var snowTheme = Theme{
 		TextColor:       tcell.GetColor("#2e3440"),
 		BackGroundColor: tcell.GetColor("#e5e9f0"),
 	},
+	ProcPanel: PanelStyle{
+		BorderColor:     tcell.GetColor("#d8dee9"),
+		TitleColor:      tcell.GetColor("#5e81ac"),
+		TextColor:       tcell.GetColor("#2e3440"),
+		BackGroundColor: tcell.GetColor("#e5e9f0"),
+	},
+	NetPanel: PanelStyle{
+		BorderColor:     tcell.GetColor("#d8dee9"),
+		TitleColor:      tcell.GetColor("#5e81ac"),
+		TextColor:       tcell.GetColor("#2e3440"),
+		BackGroundColor: tcell.GetColor("#e5e9f0"),
+	},
 	BarRed:          tcell.GetColor("#bf616a"),
 	BarYellow:       tcell.GetColor("#ebcb8b"),
 	BarGreen:        tcell.GetColor("#a3be8c"),
+	StackColors:     []tcell.Color{tcell.GetColor("#bf616a"), tcell.GetColor("#5e81ac"), tcell.GetColor("#d08770"), tcell.GetColor("#b48ead"), tcell.GetColor("#8fbcbb"), tcell.GetColor("#ebcb8b"), tcell.GetColor("#a3be8c")},
 	Backgroundcolor: tcell.GetColor("#eceff4"),
 
 	DropDownOptionStyle:   tcell.StyleDefault.Foreground(tcell.GetColor("#2e3440")).Background(tcell.GetColor("#eceff4a4")),
 	DropDownSelectedStyle: tcell.StyleDefault.Foreground(tcell.GetColor("#eceff4")).Background(tcell.GetColor("#5e81ac")),
 }
-var themesList []string
+var themesList = []string{"Default", "Nord", "Snow Day"}
+
+func themeByName(name string) *Theme {
+	switch name {
+	case "Nord":
+		return &nordTheme
+	case "Snow Day":
+		return &snowTheme
+	default:
+		return &defaultTheme
+	}
+}
 
 func saveToFile(prefs UserPreferences) {
 	configDir, _ := os.UserConfigDir()
@@ -209,371 +302,453 @@ func loadOrCreateUsersPreferences() {
 	if os.IsNotExist(err) {
 		os.WriteFile(fullPath, []byte(defaultUserPreferencesTOML), 0644)
 	}
+	// Keys missing from the file keep these defaults.
+	userPrefs = defaultPreferences()
 	toml.DecodeFile(fullPath, &userPrefs)
 
 }
-func applyTheme(theme *Theme, cpuPanel, memPanel, infoPanel, tempPanel, diskPanel, keyBindMenu *tview.TextView, grid *tview.Grid, themeSelector *tview.DropDown, settings *tview.Form) {
-	cpuPanel.SetBorderColor(theme.CPUPanel.BorderColor)
-	cpuPanel.SetTitleColor(theme.CPUPanel.TitleColor)
-	cpuPanel.SetTextColor(theme.CPUPanel.TextColor)
-	cpuPanel.SetBackgroundColor(theme.CPUPanel.BackGroundColor)
-
-	memPanel.SetBorderColor(theme.MemPanel.BorderColor)
-	memPanel.SetTitleColor(theme.MemPanel.TitleColor)
-	memPanel.SetTextColor(theme.MemPanel.TextColor)
-	memPanel.SetBackgroundColor(theme.MemPanel.BackGroundColor)
-
-	infoPanel.SetBorderColor(theme.InfoPanel.BorderColor)
-	infoPanel.SetTitleColor(theme.InfoPanel.TitleColor)
-	infoPanel.SetTextColor(theme.InfoPanel.TextColor)
-	infoPanel.SetBackgroundColor(theme.InfoPanel.BackGroundColor)
-
-	tempPanel.SetBorderColor(theme.TempPanel.BorderColor)
-	tempPanel.SetTitleColor(theme.TempPanel.TitleColor)
-	tempPanel.SetTextColor(theme.TempPanel.TextColor)
-	tempPanel.SetBackgroundColor(theme.TempPanel.BackGroundColor)
-
-	diskPanel.SetBorderColor(theme.DiskPanel.BorderColor)
-	diskPanel.SetTitleColor(theme.DiskPanel.TitleColor)
-	diskPanel.SetTextColor(theme.DiskPanel.TextColor)
-	diskPanel.SetBackgroundColor(theme.DiskPanel.BackGroundColor)
-
-	keyBindMenu.SetBorderColor(theme.InfoPanel.BorderColor)
-	keyBindMenu.SetTextColor(theme.InfoPanel.TextColor)
-	keyBindMenu.SetBackgroundColor(theme.Backgroundcolor)
-	tview.Styles.PrimitiveBackgroundColor = theme.Backgroundcolor
-	grid.SetBackgroundColor(theme.Backgroundcolor)
-
-	themeSelector.SetLabelColor(theme.InfoPanel.TitleColor)
-	themeSelector.SetFieldTextColor(theme.InfoPanel.TextColor)
-	themeSelector.SetFieldBackgroundColor(theme.InfoPanel.BackGroundColor)
-	themeSelector.SetListStyles(theme.DropDownOptionStyle, theme.DropDownSelectedStyle)
-	settings.SetBackgroundColor(theme.Backgroundcolor)
 
+// defaultPreferences are the preferences of the sections missing from the
+// config file.
+func defaultPreferences() UserPreferences {
+	return UserPreferences{
+		AlertActions: defaultAlertActionSettings,
+		Disks:        defaultDiskSettings,
+		Info:         defaultInfoSettings,
+		Fleet:        defaultFleetSettings,
+		Log:          defaultLogSettings,
+		Format:       defaultFormatSettings,
+	}
 }
+
+// formatBytes writes a size with the chosen units (IEC or SI) and precision,
+// e.g. "1.50 GiB".
 func formatBytes(value uint64) string {
-	const base = 1024
-	var returnString string
-
-	if value >= 1099511627776 {
-		returnValue := float64(value) / float64(1099511627776)
-		returnString = fmt.Sprintf("%.2f TiB", returnValue)
-	} else if value >= 1073741824 {
-		returnValue := float64(value) / float64(1073741824)
-		returnString = fmt.Sprintf("%.2f GiB", returnValue)
-	} else if value >= 1048576 {
-		returnValue := float64(value) / float64(1048576)
-		returnString = fmt.Sprintf("%.2f MiB", returnValue)
-	} else if value >= base {
-		returnValue := float64(value) / float64(base)
-		returnString = fmt.Sprintf("%.2f KiB", returnValue)
-
-	} else if value < base {
-		returnString = fmt.Sprintf("%d B", value)
-
-	}
-	return returnString
-}
-func createBar(theme *Theme, percent float64, filledChar, emptyChar string) (string, string) {
-	filledBlocks := int((percent / 100.0) * float64(barWidth))
-	var colorCode string
-	if percent >= 80 {
-		colorCode = fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
-	} else if percent >= 50 {
-		colorCode = fmt.Sprintf("[%s]", theme.BarYellow.TrueColor().String())
-	} else {
-		colorCode = fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
+	base, units := 1024.0, []string{"KiB", "MiB", "GiB", "TiB"}
+	if strings.EqualFold(userPrefs.Format.Units, "si") {
+		base, units = 1000, []string{"kB", "MB", "GB", "TB"}
+	}
+	if float64(value) < base {
+		return fmt.Sprintf("%d B", value)
+	}
+	returnValue := float64(value)
+	var unit string
+	for _, unit = range units {
+		returnValue /= base
+		if returnValue < base {
+			break
+		}
 	}
-	filledString := strings.Repeat(filledChar, filledBlocks)
-	emptyString := strings.Repeat(emptyChar, barWidth-filledBlocks)
-	return colorCode + "[" + filledString + emptyString + "]" + "[-]", colorCode
+	return formatFloat(returnValue) + " " + unit
 }
-func updateInfos(app *tview.Application, cpuPanel, memPanel, infoPanel, diskPanel, tempPanel *tview.TextView, theme *Theme, staticInfo *StaticInfo) {
+func (d *dashboard) updateInfos(theme *Theme, sample *Sample, firingPanels map[string]bool) {
+	staticInfo := d.staticInfo
 	//General Info
-	OSPlatform := staticInfo.OS
-	OSFamily := staticInfo.OSFamily
-	OSVersion := staticInfo.OSVersion
-	KernelVersion := staticInfo.KernelVersion
-	cpuModelName := staticInfo.CPUModel
-	currentTime := time.Now()
-	formatedTime := currentTime.Format("2006-01-02 15:04:05")
-	OSArch := staticInfo.KernelArch
-	hostInfo, _ := host.Info()
-	hostname := staticInfo.Hostname
-	uptime := hostInfo.Uptime
-	var uptimeInt int
-	uptimeInt = int(uptime)
-	uptimeString := time.Duration(uptimeInt) * time.Second
-	logo := staticInfo.Logo
-	OSInfoText := fmt.Sprintf("%s❄ OS: %s %s\n❄ OS family: %s\n❄ OS version: %s\n❄ Kernel Version: %s\n❄ Hostname: %s\n❄ Uptime: %s\n❄ Current date: %s\nCPU Model: %s", logo, OSPlatform, OSArch, OSFamily, OSVersion, KernelVersion, hostname, uptimeString, formatedTime, cpuModelName)
+	OSInfoText := renderInfo(staticInfo, sample, userPrefs.Info)
 
 	//Memory
-	v, _ := mem.VirtualMemory()
-	usedMemPercent := v.UsedPercent
-	totalMemString := formatBytes(v.Total)
-	usedMemString := formatBytes(v.Used)
+	totalMem, usedMem, usedMemPercent, memLimited := effectiveMemory(sample)
+	totalMemString := formatBytes(totalMem)
+	usedMemString := formatBytes(usedMem)
 
 	var usedMemPercentString string
 	if usedMemPercent >= 80 {
 		colorCode := fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
-		usedMemPercentString = fmt.Sprintf("%s%.2f[-]", colorCode, usedMemPercent)
+		usedMemPercentString = fmt.Sprintf("%s%s[-]", colorCode, formatFloat(usedMemPercent))
 	} else if usedMemPercent >= 50 {
 		colorCode := fmt.Sprintf("[%s]", theme.BarYellow.TrueColor().String())
-		usedMemPercentString = fmt.Sprintf("%s%.2f[-]", colorCode, usedMemPercent)
+		usedMemPercentString = fmt.Sprintf("%s%s[-]", colorCode, formatFloat(usedMemPercent))
 	} else {
 		colorCode := fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
-		usedMemPercentString = fmt.Sprintf("%s%.2f[-]", colorCode, usedMemPercent)
+		usedMemPercentString = fmt.Sprintf("%s%s[-]", colorCode, formatFloat(usedMemPercent))
 
 	}
-	memUsageBar, memColCode := createBar(theme, usedMemPercent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar)
-	memBarString := fmt.Sprintf("Memory: %s", memUsageBar)
-	memText := fmt.Sprintf("Total Memory: %s\nUsed Memory: %s (%s%%)\n%s%s[-]", totalMemString, usedMemString, usedMemPercentString, memColCode, memBarString)
+	// The bars fill the panels, besides the text on their lines.
+	memText := fitBars(d.memPanel, func(memBarWidth int) string {
+		if memLimited {
+			limitBar, colorCode := createBar(theme, usedMemPercent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, memBarWidth)
+			return fmt.Sprintf(tr("Memory limit (cgroup v%d): %s")+"\n"+tr("Used Memory: %s (%s%%)")+"\n%s %s%s[-]\n"+tr("Host memory: %s (%s available)")+"\n%s\n%s", sample.Cgroup.Version, totalMemString, usedMemString, usedMemPercentString, limitBar, colorCode, formatPercent(usedMemPercent), formatBytes(sample.MemTotal), formatBytes(sample.MemAvailable), memoryBreakdown(theme, sample, memBarWidth), swapText(theme, sample, memBarWidth))
+		}
+		return fmt.Sprintf(tr("Total Memory: %s")+"\n"+tr("Used Memory: %s (%s%%)")+"\n"+tr("Available Memory: %s")+"\n%s\n%s", totalMemString, usedMemString, usedMemPercentString, formatBytes(sample.MemAvailable), memoryBreakdown(theme, sample, memBarWidth), swapText(theme, sample, memBarWidth))
+	})
 
 	//CPU
 
 	cpuCountPhys := staticInfo.CPUPhysCore
 	cpuCountLogical := staticInfo.CPULogCore
-	globalCpuUse, _ := cpu.Percent(0, false)
-	globalCpuUseFloat := globalCpuUse[0]
+	globalCpuUseFloat, cpuLimited := effectiveCPU(sample)
 	var globalCpuUseString string
 	if globalCpuUseFloat >= 80 {
 		colorCode := fmt.Sprintf("[%s]", theme.BarRed.TrueColor().String())
-		globalCpuUseString = fmt.Sprintf("%s%.2f%%[-]", colorCode, globalCpuUseFloat)
+		globalCpuUseString = fmt.Sprintf("%s%s[-]", colorCode, formatPercent(globalCpuUseFloat))
 
 	} else if globalCpuUseFloat >= 50 {
 		colorCode := fmt.Sprintf("[%s]", theme.BarYellow.TrueColor().String())
-		globalCpuUseString = fmt.Sprintf("%s%.2f%%[-]", colorCode, globalCpuUseFloat)
+		globalCpuUseString = fmt.Sprintf("%s%s[-]", colorCode, formatPercent(globalCpuUseFloat))
 	} else {
 		colorCode := fmt.Sprintf("[%s]", theme.BarGreen.TrueColor().String())
-		globalCpuUseString = fmt.Sprintf("%s%.2f%%[-]", colorCode, globalCpuUseFloat)
+		globalCpuUseString = fmt.Sprintf("%s%s[-]", colorCode, formatPercent(globalCpuUseFloat))
 	}
 
-	var barStrings string
-	allCoresUsage, _ := cpu.Percent(0, true)
-	for i := range allCoresUsage {
-		currentCorePercentBar, colorCode := createBar(theme, allCoresUsage[i], userPrefs.BarFilledChar, userPrefs.BarEmptyChar)
-		barStrings = fmt.Sprintf("%s%s\nCPU%d[-] %s %s%.0f%%[-]", barStrings, colorCode, i, currentCorePercentBar, colorCode, allCoresUsage[i])
-	}
-	cpuCountText := fmt.Sprintf("CPU count physical/logical: %v/%v\nTotal usage: %s%s", cpuCountPhys, cpuCountLogical, globalCpuUseString, barStrings)
+	cpuCount := orUnavailable(theme, staticInfo.Unavailable["cores"], fmt.Sprintf("%d/%d", cpuCountPhys, cpuCountLogical))
+	cpuCountText := fitBars(d.cpuPanel, func(cpuBarWidth int) string {
+		usage := globalCpuUseString
+		if cpuLimited {
+			quotaBar, colorCode := createBar(theme, globalCpuUseFloat, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, cpuBarWidth)
+			usage = fmt.Sprintf(tr("%s of %s CPUs (cgroup quota)")+"\n%s %s%s[-]", usage, formatFloat(sample.Cgroup.CPUQuota), quotaBar, colorCode, formatPercent(globalCpuUseFloat))
+		}
+		var barStrings string
+		allCoresUsage := sample.CPUPerCore
+		for i := range allCoresUsage {
+			currentCorePercentBar, colorCode := createBar(theme, allCoresUsage[i], userPrefs.BarFilledChar, userPrefs.BarEmptyChar, cpuBarWidth)
+			barStrings = fmt.Sprintf("%s%s\nCPU%d[-] %s %s%s%%[-]", barStrings, colorCode, i, currentCorePercentBar, colorCode, formatNumber(allCoresUsage[i], 0))
+		}
+		return fmt.Sprintf(tr("CPU count physical/logical: %s")+"\n"+tr("Total usage: %s")+"\n%s\n%s%s", cpuCount, usage, loadAverageText(theme, sample, cpuCountLogical), cpuTimesText(theme, sample.CPUTimes, cpuBarWidth), barStrings)
+	})
 
 	//Disk
+	diskUsageText := renderDisks(theme, sample.Disks, userPrefs.Disks, panelWidth(d.diskPanel))
 
-	var diskUsageText string
-	partitions, _ := disk.Partitions(false)
-	var diskText string
+	// The panels whose probe failed show why instead
+	memText = orUnavailable(theme, sample.Unavailable["memory"], memText)
+	cpuCountText = orUnavailable(theme, sample.Unavailable["cpu"], cpuCountText)
+	diskUsageText = orUnavailable(theme, sample.Unavailable["disks"], diskUsageText)
 
-	for i := range partitions {
-		usage, _ := disk.Usage(partitions[i].Mountpoint)
-		totalSpaceString := formatBytes(usage.Total)
-		usedSpaceString := formatBytes(usage.Used)
+	//Temperature
+	updateTempRanges(d.tempRanges, sample.Temps)
+	tempText := orUnavailable(theme, sample.Unavailable["temps"], fitBars(d.tempPanel, func(width int) string {
+		return renderTemperatures(theme, sample.Temps, d.tempRanges, userPrefs.Sensors, false, width)
+	}))
+
+	//Other tabs
+	networkText := orUnavailable(theme, sample.Unavailable["network"], renderNetwork(theme, sample.Network))
+	storageText := orUnavailable(theme, sample.Unavailable["disks"], renderDisks(theme, sample.Disks, userPrefs.Disks, panelWidth(d.storagePanel)))
+	diskIOText := orUnavailable(theme, sample.Unavailable["diskio"], renderDiskIO(sample.DiskIO))
+	sensorsText := orUnavailable(theme, sample.Unavailable["temps"], fitBars(d.sensorsPanel, func(width int) string {
+		return renderTemperatures(theme, sample.Temps, d.tempRanges, userPrefs.Sensors, true, width)
+	}))
+	hardwareText := renderHardware(theme, staticInfo, sample)
+	sessionsText := orUnavailable(theme, sample.Unavailable["sessions"], renderSessions(theme, sample.Sessions, sample.Time))
+	updatePressureHistory(d.pressureHistory, sample.Pressure)
+	pressureText := fitBars(d.pressurePanel, func(width int) string {
+		return renderPressure(theme, sample.Pressure, d.pressureHistory, width)
+	})
+	//Update
+	d.app.QueueUpdateDraw(func() {
+		d.infoPanel.SetText(OSInfoText)
+		d.memPanel.SetText(memText)
+		d.cpuPanel.SetText(cpuCountText)
+		d.diskPanel.SetText(diskUsageText)
+		d.tempPanel.SetText(tempText)
+
+		d.updateProcesses(theme, sample.Processes, sample.Unavailable["processes"])
+		d.networkPanel.SetText(networkText)
+		d.storagePanel.SetText(storageText)
+		d.diskIOPanel.SetText(diskIOText)
+		d.sensorsPanel.SetText(sensorsText)
+		d.hardwarePanel.SetText(hardwareText)
+		d.sessionsPanel.SetText(sessionsText)
+		d.pressurePanel.SetText(pressureText)
+
+		highlightPanel(d.cpuPanel, theme.CPUPanel, theme, firingPanels["cpu"])
+		highlightPanel(d.memPanel, theme.MemPanel, theme, firingPanels["memory"])
+		highlightPanel(d.diskPanel, theme.DiskPanel, theme, firingPanels["disk"])
+		highlightPanel(d.tempPanel, theme.TempPanel, theme, firingPanels["temp"])
+	})
+}
 
-		diskBar, _ := createBar(theme, usage.UsedPercent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar)
-		diskText = fmt.Sprintf("%s%s: %s %.2f%% Used(%s/%s)\n", diskText, usage.Path, diskBar, usage.UsedPercent, usedSpaceString, totalSpaceString)
+// loadAverageText shows the 1, 5 and 15 minutes load averages, each colored
+// by how loaded the logical cores are.
+func loadAverageText(theme *Theme, sample *Sample, logicalCores int) string {
+	if reason := sample.Unavailable["load"]; reason != "" {
+		return tr("Load average:") + " " + orUnavailable(theme, reason, "")
 	}
-	diskUsageText = diskText
-	//Temperature
+	if logicalCores < 1 {
+		logicalCores = 1
+	}
+	var loads []string
+	for _, loadAvg := range []float64{sample.Load1, sample.Load5, sample.Load15} {
+		colorCode := barColor(theme, loadAvg/float64(logicalCores)*100)
+		loads = append(loads, fmt.Sprintf("%s%s[-]", colorCode, formatFloat(loadAvg)))
+	}
+	return fmt.Sprintf(tr("Load average: %s (%s%% of %d cores)"), strings.Join(loads, " "), formatNumber(sample.Load1/float64(logicalCores)*100, 0), logicalCores)
+}
 
-	temperatures, _ := sensors.SensorsTemperatures()
-	var cpuText string
-	for i := range temperatures {
-		if strings.Contains(temperatures[i].SensorKey, "coretemp") || strings.Contains(temperatures[i].SensorKey, "k10temp") || strings.Contains(temperatures[i].SensorKey, "ackage") {
-			cpuTemp := temperatures[i].Temperature
-			cpuText = cpuText + fmt.Sprintf("%s : %.2fC\n", temperatures[i].SensorKey, cpuTemp)
+// cpuTimesText splits the CPU time in user, system, iowait, irq, softirq,
+// steal and nice. A VM waiting on its disk (iowait) or on its host (steal)
+// shows up here instead of looking like real CPU load.
+func cpuTimesText(theme *Theme, times CPUTimesSample, width int) string {
+	segments := []stackSegment{
+		{"user", times.User, formatNumber(times.User, 1) + "%"},
+		{"system", times.System, formatNumber(times.System, 1) + "%"},
+		{"iowait", times.Iowait, formatNumber(times.Iowait, 1) + "%"},
+		{"irq", times.Irq, formatNumber(times.Irq, 1) + "%"},
+		{"softirq", times.Softirq, formatNumber(times.Softirq, 1) + "%"},
+		{"steal", times.Steal, formatNumber(times.Steal, 1) + "%"},
+		{"nice", times.Nice, formatNumber(times.Nice, 1) + "%"},
+	}
+	bar, legend := createStackedBar(theme, segments, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+	return fmt.Sprintf(tr("CPU time: %s")+"\n%s", bar, legend)
+}
 
-		}
+// memoryBreakdown splits the memory in used, buffers, cached, shared and free.
+// On Linux the cached memory (which includes the shared memory) can be
+// reclaimed, so a high "used" percentage alone doesn't mean memory is short.
+func memoryBreakdown(theme *Theme, sample *Sample, width int) string {
+	if sample.MemTotal == 0 {
+		return ""
+	}
+	percent := func(value uint64) float64 {
+		return float64(value) / float64(sample.MemTotal) * 100
+	}
+	cached := sample.MemCached
+	if sample.MemShared <= cached {
+		cached -= sample.MemShared
+	}
+	segments := []stackSegment{
+		{tr("Used"), percent(sample.MemUsed), formatBytes(sample.MemUsed)},
+		{tr("Buffers"), percent(sample.MemBuffers), formatBytes(sample.MemBuffers)},
+		{tr("Cached"), percent(cached), formatBytes(cached)},
+		{tr("Shared"), percent(sample.MemShared), formatBytes(sample.MemShared)},
+	}
+	bar, legend := createStackedBar(theme, segments, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+	return fmt.Sprintf(tr("Memory: %s")+"\n%s ■ "+tr("Free %s")+"\n"+tr("Dirty: %s  Writeback: %s"), bar, legend, formatBytes(sample.MemFree), formatBytes(sample.MemDirty), formatBytes(sample.MemWriteBack))
+}
 
+func swapText(theme *Theme, sample *Sample, width int) string {
+	if reason := sample.Unavailable["swap"]; reason != "" {
+		return fmt.Sprintf(tr("Swap: %s"), orUnavailable(theme, reason, ""))
 	}
-	if cpuText == "" {
-		cpuText = "No temperature sensors found."
+	if sample.SwapTotal == 0 {
+		return tr("Swap: none")
 	}
-	//Update
-	app.QueueUpdateDraw(func() {
-		infoPanel.SetText(OSInfoText)
-		memPanel.SetText(memText)
-		cpuPanel.SetText(cpuCountText)
-		diskPanel.SetText(diskUsageText)
-		tempPanel.SetText(cpuText)
+	swapBar, swapColCode := createBar(theme, sample.SwapUsedPercent, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+	return fmt.Sprintf(tr("Swap: %s %s%s[-] (%s/%s)")+"\n"+tr("Swap in: %s/s  Swap out: %s/s"), swapBar, swapColCode, formatPercent(sample.SwapUsedPercent), formatBytes(sample.SwapUsed), formatBytes(sample.SwapTotal), formatBytes(uint64(sample.SwapInRate)), formatBytes(uint64(sample.SwapOutRate)))
+}
 
-	})
+// orUnavailable is the text of a panel, or the reason its probe failed.
+func orUnavailable(theme *Theme, reason, text string) string {
+	if reason == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s]%s[-]", theme.BarRed.TrueColor().String(), tview.Escape(fmt.Sprintf(tr("unavailable: %s"), reason)))
 }
+
+// highlightPanel draws the border of a panel with a firing alert in the
+// theme's red, and puts the normal border back once it is resolved.
+func highlightPanel(panel *tview.TextView, style PanelStyle, theme *Theme, firing bool) {
+	if firing {
+		panel.SetBorderColor(theme.BarRed)
+		panel.SetTitleColor(theme.BarRed)
+	} else {
+		panel.SetBorderColor(style.BorderColor)
+		panel.SetTitleColor(style.TitleColor)
+	}
+}
+
+// runningScreen is the screen of the dashboard while it is shown, to put
+// the terminal back in its normal state if TermiDash panics.
+var runningScreen tcell.Screen
+
+// exitOnPanic restores the terminal and prints the panic, which would be lost
+// on a screen left in raw mode. It is deferred first by main and by every
+// goroutine that runs while the dashboard is shown.
+func exitOnPanic() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if runningScreen != nil {
+		runningScreen.Fini()
+	}
+	fmt.Fprintf(os.Stderr, "TermiDash crashed: %v\n\n%s", p, debug.Stack())
+	os.Exit(2)
+}
+
 func main() {
+	defer exitOnPanic()
+	intervalFlag := flag.Duration("interval", 0, "refresh interval, e.g. 500ms or 2s (overrides RefreshInterval from the config)")
+	agentFlag := flag.Bool("agent", false, "don't show the dashboard, serve this machine's samples to TermiDash clients instead")
+	listenFlag := flag.String("listen", agentDefaultAddress, "address the agent listens on, host:port or unix:/path/to.sock")
+	connectFlag := flag.String("connect", "", "show the dashboard of the agent at this address, host:port or unix:/path/to.sock")
+	logFileFlag := flag.String("log-file", "", "append every sample to this CSV or NDJSON file (overrides File from the [Log] config)")
+	fetchFlag := flag.Bool("fetch", false, "print the logo, the system information and the usage bars, then exit")
+	noTUIFlag := flag.Bool("no-tui", false, "don't show the dashboard, only write the --log-file")
+	fleetMemberFlag := flag.Bool("fleet-member", false, "used by the fleet page: leave out the fleet, custom panels, log tails and log of the config")
+	flag.Parse()
 	loadOrCreateUsersPreferences()
-	if userPrefs.ThemeName != "" {
-		switch userPrefs.ThemeName {
-		case "Nord":
-			currentTheme = &nordTheme
-		case "Snow Day":
-			currentTheme = &snowTheme
-		case "Default":
-			currentTheme = &defaultTheme
-		default:
-			currentTheme = &defaultTheme
-		}
+	loadLanguage(userPrefs.Language)
+	refreshInterval := userPrefs.RefreshInterval
+	if *intervalFlag > 0 {
+		refreshInterval = *intervalFlag
 	}
-	staticPlatform, staticFam, staticVersion, _ := host.PlatformInformation()
-	logoToSearch := staticPlatform
-	if strings.Contains(logoToSearch, "Microsoft Windows 10") {
-		logoToSearch = "windows10"
-	} else if strings.Contains(logoToSearch, "Microsoft Windows 11") {
-		logoToSearch = "windows11"
-	} else if strings.Contains(logoToSearch, "macOS") || staticFam == "Darwin" {
-		logoToSearch = "macos"
-	} else if strings.Contains(staticVersion, "kali") {
-		logoToSearch = "kali"
-	}
-	logoBytes, err := logoFiles.ReadFile("logos/" + logoToSearch + ".ascii")
-	var logo string
-	if err == nil {
-		translatedLogo := tview.TranslateANSI(string(logoBytes))
-		logo = translatedLogo + "\n"
+	refresh := newRefreshControl(refreshInterval)
+	currentTheme = themeByName(userPrefs.ThemeName)
+
+	var source sampleSource
+	var staticInfo StaticInfo
+	if *connectFlag != "" {
+		remote := newRemoteSource(*connectFlag)
+		remoteInfo, err := remote.Static(15 * time.Second)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "TermiDash:", err)
+			os.Exit(1)
+		}
+		staticInfo = *remoteInfo
+		source = remote
 	} else {
-		logo = ""
-	}
-	cpuInfo, _ := cpu.Info()
-	cpuPhys, _ := cpu.Counts(false)
-	cpuLog, _ := cpu.Counts(true)
-	cpuModelName := cpuInfo[0].ModelName
-	hostInfo, _ := host.Info()
-	hostname := hostInfo.Hostname
-	firstChar := strings.ToUpper(string(staticPlatform[0]))
-	staticPlatform = firstChar + staticPlatform[1:]
-	kernelVersion, _ := host.KernelVersion()
-	kernelArch, _ := host.KernelArch()
-	staticInfo := StaticInfo{
-		Logo:          logo,
-		OS:            staticPlatform,
-		OSFamily:      staticFam,
-		OSVersion:     staticVersion,
-		KernelVersion: kernelVersion,
-		KernelArch:    kernelArch,
-		Hostname:      hostname,
-		CPUPhysCore:   cpuPhys,
-		CPULogCore:    cpuLog,
-		CPUModel:      cpuModelName,
-	}
-	themesList = append(themesList, "Default", "Nord", "Snow Day")
-	//CPU section
-
-	//cpuManufacturer := cpuInfo[0].VendorID
-	cpuPanel := tview.NewTextView()
-	cpuPanel.SetScrollable(true)
-	cpuPanel.SetBorder(true)
-	cpuPanel.SetTitle("CPU")
-	cpuPanel.SetDynamicColors(true)
-	cpuPanel.ScrollToBeginning()
-	//FastFetch-style section
-
-	infoPanel := tview.NewTextView()
-	infoPanel.SetBorder(true)
-	infoPanel.SetTitle("System Information")
-	infoPanel.SetDynamicColors(true)
-	//Memory section
-	memPanel := tview.NewTextView()
-	memPanel.SetBorder(true)
-	memPanel.SetTitle("Memory")
-	memPanel.SetDynamicColors(true)
-
-	//Disk section
-
-	diskPanel := tview.NewTextView()
-	diskPanel.SetBorder(true)
-	diskPanel.SetTitle("Disk Usage")
-	diskPanel.SetDynamicColors(true)
-
-	// Temperature section
-	tempPanel := tview.NewTextView()
-	tempPanel.SetBorder(true)
-	tempPanel.SetTitle("Temperatures")
-	tempPanel.SetDynamicColors(true)
-
-	// General Layout
-	rightColumnLayout := tview.NewFlex().SetDirection(tview.FlexRow)
-	rightColumnLayout.AddItem(cpuPanel, 0, 1, true)
-	rightColumnLayout.AddItem(memPanel, 0, 1, false)
-	rightColumnLayout.AddItem(tempPanel, 0, 1, false)
-	app := tview.NewApplication()
-	mainGrid := tview.NewGrid()
-	mainGrid.SetRows(0, 0, 10)
-	mainGrid.SetColumns(0, 0)
-	mainGrid.SetBorder(true)
-	mainGrid.AddItem(diskPanel, 2, 0, 1, 2, 0, 0, false)
-	mainGrid.AddItem(infoPanel, 0, 0, 2, 1, 0, 0, false)
-	mainGrid.AddItem(rightColumnLayout, 0, 1, 2, 1, 0, 0, false)
-	settings := tview.NewForm()
-	settings.SetBorder(true)
-	settings.SetTitle("Settings - ESC or 's' to go back")
-	themeSelector := tview.NewDropDown()
-	themeSelector.SetLabel("Select a theme (hit Enter): ")
-	themeSelector.SetOptions(themesList, nil)
-	themeSelector.SetCurrentOption(0)
-	settings.AddFormItem(themeSelector)
-
-	keyBindMenu := tview.NewTextView()
-	keyBindMenu.SetBorder(true)
-	keyBindMenu.SetTitle("Keybinds - ESC or 'h' to go back")
-	keyBindMenu.SetText("'q'/CTRL + C - quit the application\n's' - open the settings page\nTAB/Arrow keys - navigate in the settings page\nESC - quit the settings/help page\n'h' - open the help page (this page)\n\n\nMade by @Hash-AK (https://github.com/hash-ak)")
-	applyTheme(currentTheme, cpuPanel, memPanel, infoPanel, tempPanel, diskPanel, keyBindMenu, mainGrid, themeSelector, settings)
-
-	pages := tview.NewPages()
-	pages.AddPage("settings", settings, true, false)
-	pages.AddPage("help", keyBindMenu, true, false)
-	pages.AddPage("dashboard", mainGrid, true, true)
-	app.SetRoot(pages, true)
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Rune() == 'q' {
-			app.Stop()
-			return nil
+		staticInfo = collectStaticInfo()
+		source = newCollector()
+	}
+	if *fetchFlag {
+		sample, err := fetchSample(source)
+		if sample == nil {
+			fmt.Fprintln(os.Stderr, "TermiDash:", err)
+			os.Exit(1)
 		}
-		currentPage, _ := pages.GetFrontPage()
-		if event.Rune() == 's' {
-			if currentPage == "dashboard" {
-				pages.SwitchToPage("settings")
-			} else if currentPage == "settings" {
-				pages.SwitchToPage("dashboard")
-			}
-			return nil
+		fmt.Print(renderFetch(currentTheme, &staticInfo, sample))
+		return
+	}
+	// The log of the config is this machine's, not the one of an agent.
+	logPath := userPrefs.Log.File
+	if *connectFlag != "" {
+		logPath = ""
+	}
+	if *logFileFlag != "" {
+		logPath = *logFileFlag
+	}
+	var logger *metricsLogger
+	if logPath != "" && !*fleetMemberFlag {
+		var err error
+		logger, err = newMetricsLogger(logPath, userPrefs.Log, staticInfo.Hostname)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "TermiDash:", err)
+			os.Exit(1)
 		}
-		if event.Rune() == 'h' {
-			if currentPage == "dashboard" {
-				pages.SwitchToPage("help")
-			} else if currentPage == "help" {
-				pages.SwitchToPage("dashboard")
-			}
+		defer logger.Close()
+	}
+	if *agentFlag {
+		if logger != nil {
+			source = loggingSource{source, logger}
 		}
-		if event.Key() == tcell.KeyEscape {
-			if currentPage == "settings" || currentPage == "help" {
-				pages.SwitchToPage("dashboard")
-			}
+		interval, _ := refresh.State()
+		if err := runAgent(*listenFlag, &staticInfo, source, interval); err != nil {
+			fmt.Fprintln(os.Stderr, "TermiDash:", err)
+			os.Exit(1)
 		}
-		return event
-	})
-	settings.AddButton("Save and close", func() {
-		_, selection := themeSelector.GetCurrentOption()
-		switch selection {
-		case "Default":
-			currentTheme = &defaultTheme
-			applyTheme(currentTheme, cpuPanel, memPanel, infoPanel, tempPanel, diskPanel, keyBindMenu, mainGrid, themeSelector, settings)
-
-		case "Nord":
-			currentTheme = &nordTheme
-			applyTheme(currentTheme, cpuPanel, memPanel, infoPanel, tempPanel, diskPanel, keyBindMenu, mainGrid, themeSelector, settings)
-		case "Snow Day":
-			currentTheme = &snowTheme
-			applyTheme(currentTheme, cpuPanel, memPanel, infoPanel, tempPanel, diskPanel, keyBindMenu, mainGrid, themeSelector, settings)
+		return
+	}
+	if *noTUIFlag {
+		if logger == nil {
+			fmt.Fprintln(os.Stderr, "TermiDash: --no-tui needs --log-file")
+			os.Exit(1)
 		}
-		userPrefs.ThemeName = selection
-		saveToFile(userPrefs)
-		pages.SwitchToPage("dashboard")
-
-	})
+		interval, _ := refresh.State()
+		runLogger(source, logger, interval)
+		return
+	}
+	extras := configExtras(userPrefs, *fleetMemberFlag)
+	if err := checkPanelTabs(extras.Panels, extras.Tails); err != nil {
+		fmt.Fprintln(os.Stderr, "TermiDash:", err)
+		os.Exit(1)
+	}
+	app := tview.NewApplication()
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "TermiDash:", err)
+		os.Exit(1)
+	}
+	runningScreen = screen
+	app.SetScreen(screen)
+	alerts := newAlertEngine(userPrefs.Alerts)
+	notifier := newAlertNotifier(userPrefs.AlertActions, staticInfo.Hostname)
+	d := newDashboard(app, &staticInfo, refresh, alerts, extras)
+	status := d.status
+	app.SetRoot(d.root, true)
+	app.SetInputCapture(d.handleKey)
+	renderStatus := func() {
+		app.QueueUpdateDraw(func() {
+			status.Render(currentTheme)
+		})
+	}
 	go func() {
+		defer exitOnPanic()
+		sourceFailed := false
+		tick := func(draw bool) {
+			sample, err := source.Collect()
+			if err != nil {
+				status.SetMessage(err.Error())
+				sourceFailed = true
+				renderStatus()
+			} else if sourceFailed {
+				status.SetMessage("")
+				sourceFailed = false
+				renderStatus()
+			}
+			if sample == nil {
+				return
+			}
+			if logger != nil {
+				if err := logger.Write(sample); err != nil {
+					status.SetMessage("log: " + err.Error())
+					renderStatus()
+				}
+			}
+			events := alerts.Evaluate(sample)
+			if len(events) > 0 {
+				for _, event := range events {
+					if event.Bell {
+						screen.Beep()
+						break
+					}
+				}
+				for _, event := range events {
+					notifier.Notify(event)
+				}
+				status.SetMessage(events[len(events)-1].Message())
+				historyText := renderAlertHistory(currentTheme, alerts)
+				app.QueueUpdateDraw(func() {
+					d.alertsPanel.SetText(historyText)
+				})
+				renderStatus()
+			}
+			if draw {
+				d.updateInfos(currentTheme, sample, alerts.FiringPanels())
+				d.fleet.Update(currentTheme, sample, alerts, time.Now())
+			}
+		}
 
-		updateInfos(app, cpuPanel, memPanel, infoPanel, diskPanel, tempPanel, currentTheme, &staticInfo)
+		tick(true)
 
-		ticker := time.NewTicker(1 * time.Second)
+		interval, _ := refresh.State()
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
-		for range ticker.C {
-			updateInfos(app, cpuPanel, memPanel, infoPanel, diskPanel, tempPanel, currentTheme, &staticInfo)
+		// The clock in the status line keeps going even when paused.
+		clock := time.NewTicker(1 * time.Second)
+		defer clock.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// Samples are still collected while paused so that the alert
+				// rules keep being evaluated, only the panels are frozen.
+				_, paused := refresh.State()
+				tick(!paused)
+			case <-refresh.changed:
+				newInterval, paused := refresh.State()
+				if newInterval != interval {
+					interval = newInterval
+					ticker.Reset(interval)
+				}
+				if !paused {
+					tick(true)
+				}
+				renderStatus()
+			case failure := <-notifier.Failures:
+				status.SetMessage(failure)
+				renderStatus()
+			case <-clock.C:
+				renderStatus()
+			}
 		}
 	}()
 	app.Run()
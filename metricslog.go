@@ -0,0 +1,390 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LogSettings is the [Log] table of the config file:
+//
+//	[Log]
+//	File = "/var/log/termidash/metrics.csv"
+//	Format = "csv"
+//	Fields = ["cpu", "memory", "disks", "temps"]
+//	MaxSize = 100
+//	RotateEvery = "24h"
+//	Compress = true
+//
+// File can be overridden with --log-file, and is only used with --connect
+// when given that way: the machine shown isn't the one File was set up on,
+// and the dashboards of a fleet would all append to it. Format is "csv" or "ndjson", and
+// defaults to the file's extension. MaxSize is in MiB. A zero MaxSize or
+// RotateEvery disables that kind of rotation.
+type LogSettings struct {
+	File        string        `toml:"File,omitempty"`
+	Format      string        `toml:"Format,omitempty"`
+	Fields      []string      `toml:"Fields"`
+	MaxSize     int64         `toml:"MaxSize"`
+	RotateEvery time.Duration `toml:"RotateEvery"`
+	Compress    bool          `toml:"Compress"`
+}
+
+var defaultLogSettings = LogSettings{
+	Fields:   []string{"cpu", "memory", "disks", "temps"},
+	MaxSize:  100,
+	Compress: true,
+}
+
+// logTimeFormat is RFC 3339 with milliseconds, for refresh intervals under a
+// second.
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// logField is one value of a log record. Names follow the alert metrics,
+// e.g. "cpu.core0" or "disk / used".
+type logField struct {
+	Name  string
+	Value float64
+}
+
+// logRecord flattens the chosen field groups of a sample.
+func logRecord(sample *Sample, groups []string) []logField {
+	var fields []logField
+	for _, group := range groups {
+		switch group {
+		case "cpu":
+			cpuPercent, _ := effectiveCPU(sample)
+			fields = append(fields, logField{"cpu.total", cpuPercent})
+			for i, core := range sample.CPUPerCore {
+				fields = append(fields, logField{fmt.Sprintf("cpu.core%d", i), core})
+			}
+		case "load":
+			fields = append(fields, logField{"load.1", sample.Load1}, logField{"load.5", sample.Load5}, logField{"load.15", sample.Load15})
+		case "memory":
+			_, used, percent, _ := effectiveMemory(sample)
+			fields = append(fields, logField{"mem.used", percent}, logField{"mem.used_bytes", float64(used)})
+		case "swap":
+			fields = append(fields, logField{"swap.used", sample.SwapUsedPercent}, logField{"swap.used_bytes", float64(sample.SwapUsed)})
+		case "disks":
+			for _, usage := range sample.Disks {
+				fields = append(fields, logField{"disk " + usage.Mountpoint + " used", usage.UsedPercent})
+			}
+		case "temps":
+			for _, temp := range sample.Temps {
+				fields = append(fields, logField{"temp " + temp.SensorKey, temp.Temperature})
+			}
+		case "network":
+			for _, iface := range sample.Network {
+				fields = append(fields, logField{"net " + iface.Name + " recv", iface.RecvRate}, logField{"net " + iface.Name + " sent", iface.SendRate})
+			}
+		}
+	}
+	return fields
+}
+
+// metricsLogger appends one record per sample to a CSV or NDJSON file, and
+// rotates it by size and/or age.
+type metricsLogger struct {
+	path     string
+	format   string
+	settings LogSettings
+	host     string // the machine the samples come from, in every record
+
+	file    *os.File
+	size    int64
+	opened  time.Time
+	columns []string // of the current CSV file
+	last    time.Time
+	// compress gzips the rotated files, compressFile but in tests.
+	compress func(path string) error
+
+	mu          sync.Mutex
+	compressErr error // of a rotated file, reported by the next Write
+}
+
+func newMetricsLogger(path string, settings LogSettings, host string) (*metricsLogger, error) {
+	format := strings.ToLower(settings.Format)
+	if format == "" {
+		format = "csv"
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".ndjson" || ext == ".jsonl" || ext == ".json" {
+			format = "ndjson"
+		}
+	}
+	if format != "csv" && format != "ndjson" {
+		return nil, fmt.Errorf("unknown log format %q, use csv or ndjson", settings.Format)
+	}
+	logger := &metricsLogger{path: path, format: format, settings: settings, host: host, compress: compressFile}
+	if err := logger.open(); err != nil {
+		return nil, err
+	}
+	return logger, nil
+}
+
+func (logger *metricsLogger) open() error {
+	os.MkdirAll(filepath.Dir(logger.path), 0755)
+	file, err := os.OpenFile(logger.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	logger.file = file
+	logger.size = info.Size()
+	logger.opened = time.Now()
+	logger.columns = nil
+	if logger.format == "csv" && logger.size > 0 {
+		logger.columns = readCSVHeader(logger.path)
+	}
+	return nil
+}
+
+// readCSVHeader returns the value columns of an existing CSV file, after its
+// time and host ones, so that appending to it keeps them.
+func readCSVHeader(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	header, err := csv.NewReader(file).Read()
+	if err != nil || len(header) < 2 || header[0] != "time" || header[1] != "host" {
+		return nil
+	}
+	return header[2:]
+}
+
+// Write appends the record of a sample, rotating the file first if needed. A
+// sample already written (an agent slower than the refresh) is skipped. The
+// record is still written when the rotation fails, and the error of a
+// compression in the background is returned by the next call.
+func (logger *metricsLogger) Write(sample *Sample) error {
+	logger.mu.Lock()
+	compressErr := logger.compressErr
+	logger.compressErr = nil
+	logger.mu.Unlock()
+	if sample.Time.Equal(logger.last) {
+		return compressErr
+	}
+	logger.last = sample.Time
+	var rotateErr error
+	if logger.needsRotation(sample.Time) {
+		rotateErr = logger.rotate(sample.Time)
+	}
+	fields := logRecord(sample, logger.settings.Fields)
+	var line []byte
+	if logger.format == "ndjson" {
+		line = ndjsonLine(sample.Time, logger.host, fields)
+	} else {
+		if logger.columns == nil {
+			// The columns are those of the first record of the file. Values
+			// that appear later (e.g. a new disk) are left out until the
+			// next rotation, missing ones are left empty.
+			for _, field := range fields {
+				logger.columns = append(logger.columns, field.Name)
+			}
+			line = csvLine(append([]string{"time", "host"}, logger.columns...))
+		}
+		line = append(line, csvLine(csvRow(sample.Time, logger.host, fields, logger.columns))...)
+	}
+	written, err := logger.file.Write(line)
+	logger.size += int64(written)
+	return errors.Join(compressErr, rotateErr, err)
+}
+
+func csvRow(t time.Time, host string, fields []logField, columns []string) []string {
+	values := make(map[string]float64, len(fields))
+	for _, field := range fields {
+		values[field.Name] = field.Value
+	}
+	row := []string{t.Format(logTimeFormat), host}
+	for _, column := range columns {
+		if value, ok := values[column]; ok {
+			row = append(row, formatLogValue(value))
+		} else {
+			row = append(row, "")
+		}
+	}
+	return row
+}
+
+// formatLogValue rounds to two decimals without padding, so that byte counts
+// stay integers. A value that isn't a number (NaN, infinite) is empty, like a
+// missing one.
+func formatLogValue(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func csvLine(cells []string) []byte {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(cells)
+	writer.Flush()
+	return []byte(builder.String())
+}
+
+// ndjsonLine writes the fields as a JSON object in their order, after the
+// time and the host. JSON has no NaN or infinity: they are written as null.
+func ndjsonLine(t time.Time, host string, fields []logField) []byte {
+	var builder strings.Builder
+	timeJSON, _ := json.Marshal(t.Format(logTimeFormat))
+	hostJSON, _ := json.Marshal(host)
+	builder.WriteString(`{"time":`)
+	builder.Write(timeJSON)
+	builder.WriteString(`,"host":`)
+	builder.Write(hostJSON)
+	for _, field := range fields {
+		name, _ := json.Marshal(field.Name)
+		builder.WriteString(",")
+		builder.Write(name)
+		builder.WriteString(":")
+		if value := formatLogValue(field.Value); value != "" {
+			builder.WriteString(value)
+		} else {
+			builder.WriteString("null")
+		}
+	}
+	builder.WriteString("}\n")
+	return []byte(builder.String())
+}
+
+func (logger *metricsLogger) needsRotation(now time.Time) bool {
+	if logger.size == 0 {
+		return false
+	}
+	if logger.settings.MaxSize > 0 && logger.size >= logger.settings.MaxSize*1024*1024 {
+		return true
+	}
+	return logger.settings.RotateEvery > 0 && now.Sub(logger.opened) >= logger.settings.RotateEvery
+}
+
+// rotate renames the current file with a timestamp ("metrics.csv" becomes
+// "metrics-20250101-120000.csv"), compresses it in the background if asked,
+// and starts a new file. When the file can't be renamed, it is reopened to
+// keep appending to it.
+func (logger *metricsLogger) rotate(now time.Time) error {
+	logger.file.Close()
+	rotated := logger.rotatedName(now)
+	if err := os.Rename(logger.path, rotated); err != nil {
+		return errors.Join(err, logger.open())
+	}
+	if logger.settings.Compress {
+		go func() {
+			defer exitOnPanic()
+			if err := logger.compress(rotated); err != nil {
+				logger.mu.Lock()
+				logger.compressErr = fmt.Errorf("compressing %s: %w", rotated, err)
+				logger.mu.Unlock()
+			}
+		}()
+	}
+	return logger.open()
+}
+
+// rotatedName is the name of the file rotated at now. The rotations of the
+// same second, with a small MaxSize, are numbered from the second one on
+// ("metrics-20250101-120000-2.csv") so as not to replace the first.
+func (logger *metricsLogger) rotatedName(now time.Time) string {
+	ext := filepath.Ext(logger.path)
+	base := strings.TrimSuffix(logger.path, ext) + "-" + now.Format("20060102-150405")
+	rotated := base + ext
+	for n := 2; fileExists(rotated) || fileExists(rotated+".gz"); n++ {
+		rotated = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	return rotated
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// compressFile gzips a rotated file into file.gz and removes it.
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(target)
+	_, err = io.Copy(writer, source)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (logger *metricsLogger) Close() error {
+	return logger.file.Close()
+}
+
+// loggingSource writes every sample of source to the log, for the agent,
+// which has no refresh tick of its own to do it.
+type loggingSource struct {
+	source sampleSource
+	logger *metricsLogger
+}
+
+func (logging loggingSource) Collect() (*Sample, error) {
+	sample, err := logging.source.Collect()
+	if sample != nil {
+		if err := logging.logger.Write(sample); err != nil {
+			fmt.Fprintln(os.Stderr, "TermiDash: log:", err)
+		}
+	}
+	return sample, err
+}
+
+// runLogger only collects and logs the samples, without the dashboard, until
+// interrupted or terminated.
+func runLogger(source sampleSource, logger *metricsLogger, interval time.Duration) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sample, err := source.Collect()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "TermiDash:", err)
+		}
+		if sample != nil {
+			if err := logger.Write(sample); err != nil {
+				fmt.Fprintln(os.Stderr, "TermiDash: log:", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			logger.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rotatingLogger logs to a CSV file of a temporary directory, rotated every
+// hour of the samples' time.
+func rotatingLogger(t *testing.T, compress bool) (*metricsLogger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	logger, err := newMetricsLogger(path, LogSettings{Fields: []string{"load"}, RotateEvery: time.Hour, Compress: compress}, "buildbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+	return logger, path
+}
+
+// loggedSample is a sample taken at the given time.
+func loggedSample(at time.Time) *Sample {
+	sample := fakeSample(0)
+	sample.Time = at
+	return sample
+}
+
+func countFileLines(t *testing.T, path string) int {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(content), "\n")
+}
+
+func TestMetricsLoggerRotation(t *testing.T) {
+	logger, path := rotatingLogger(t, true)
+	now := time.Now()
+	if err := logger.Write(loggedSample(now)); err != nil {
+		t.Fatal(err)
+	}
+	later := now.Add(2 * time.Hour)
+	if err := logger.Write(loggedSample(later)); err != nil {
+		t.Fatal(err)
+	}
+	rotated := strings.TrimSuffix(path, ".csv") + later.Format("-20060102-150405") + ".csv"
+	waitFor(t, "the rotated file to be compressed", func() bool {
+		_, err := os.Stat(rotated)
+		return os.IsNotExist(err)
+	})
+	if _, err := os.Stat(rotated + ".gz"); err != nil {
+		t.Error(err)
+	}
+	// The new file starts with its own header.
+	if lines := countFileLines(t, path); lines != 2 {
+		t.Errorf("%d lines in the new file, want the header and a record", lines)
+	}
+}
+
+func TestMetricsLoggerSameSecond(t *testing.T) {
+	logger, path := rotatingLogger(t, true)
+	compressed := make(chan string, 2)
+	logger.compress = func(path string) error {
+		compressed <- path
+		return nil
+	}
+	now := time.Now()
+	if err := logger.Write(loggedSample(now)); err != nil {
+		t.Fatal(err)
+	}
+	// The hour since the file was opened is in the samples' time only: the
+	// second write rotates again, within the same second.
+	later := now.Add(2 * time.Hour).Truncate(time.Second)
+	for _, at := range []time.Time{later, later.Add(time.Millisecond)} {
+		if err := logger.Write(loggedSample(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rotated := strings.TrimSuffix(path, ".csv") + later.Format("-20060102-150405")
+	// Compressed in any order.
+	want := []string{rotated + "-2.csv", rotated + ".csv"}
+	got := []string{<-compressed, <-compressed}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("compressed %q, want %q", got, want)
+	}
+	for _, want := range want {
+		if lines := countFileLines(t, want); lines != 2 {
+			t.Errorf("%d lines in %s, want the header and a record", lines, want)
+		}
+	}
+}
+
+func TestMetricsLoggerRenameFailure(t *testing.T) {
+	logger, path := rotatingLogger(t, false)
+	now := time.Now()
+	if err := logger.Write(loggedSample(now)); err != nil {
+		t.Fatal(err)
+	}
+	// A file removed behind the logger's back can't be renamed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Write(loggedSample(now.Add(2 * time.Hour))); err == nil {
+		t.Error("no error for the failed rotation")
+	}
+	// The record is written to the file, which was opened again.
+	if lines := countFileLines(t, path); lines != 2 {
+		t.Errorf("%d lines in the file, want the header and a record", lines)
+	}
+}
+
+func TestMetricsLoggerCompressFailure(t *testing.T) {
+	logger, _ := rotatingLogger(t, true)
+	logger.compress = func(path string) error {
+		return errors.New("no space left on device")
+	}
+	now := time.Now()
+	if err := logger.Write(loggedSample(now)); err != nil {
+		t.Fatal(err)
+	}
+	sample := loggedSample(now.Add(2 * time.Hour))
+	if err := logger.Write(sample); err != nil {
+		t.Fatal(err)
+	}
+	// The compression runs in the background, its error is returned by a
+	// later Write, even of a sample already written.
+	var err error
+	waitFor(t, "the compression error", func() bool {
+		err = logger.Write(sample)
+		return err != nil
+	})
+	if !strings.Contains(err.Error(), "compressing ") || !strings.Contains(err.Error(), "no space left on device") {
+		t.Errorf("got %q, want the compression error", err)
+	}
+	if err := logger.Write(sample); err != nil {
+		t.Errorf("got %q again", err)
+	}
+}
+
+func TestMetricsLoggerHost(t *testing.T) {
+	for _, test := range []struct {
+		file  string
+		lines []string
+	}{
+		{"metrics.csv", []string{"time,host,load.1,load.5,load.15", "2025-03-14T09:26:53.000Z,buildbox,2.5,1.75,1.2"}},
+		{"metrics.ndjson", []string{`{"time":"2025-03-14T09:26:53.000Z","host":"buildbox","load.1":2.5,"load.5":1.75,"load.15":1.2}`}},
+	} {
+		path := filepath.Join(t.TempDir(), test.file)
+		logger, err := newMetricsLogger(path, LogSettings{Fields: []string{"load"}}, "buildbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = logger.Write(loggedSample(fakeTime))
+		logger.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(path)
+		if got, want := string(content), strings.Join(test.lines, "\n")+"\n"; got != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.file, got, want)
+		}
+	}
+}
+
+func TestLogValuesNotFinite(t *testing.T) {
+	fields := []logField{{"cpu.total", 12.345}, {"temp a", math.NaN()}, {"temp b", math.Inf(1)}, {"net eth0 recv", math.Inf(-1)}}
+	line := ndjsonLine(fakeTime, "buildbox", fields)
+	var values map[string]any
+	if err := json.Unmarshal(line, &values); err != nil {
+		t.Fatalf("%s is not JSON: %v", line, err)
+	}
+	if values["cpu.total"] != 12.35 || values["temp a"] != nil || values["temp b"] != nil || values["net eth0 recv"] != nil {
+		t.Errorf("got %s, want null for the values that aren't numbers", line)
+	}
+	if got, want := strings.Join(csvRow(fakeTime, "buildbox", fields, []string{"cpu.total", "temp a", "temp b"}), ","), "2025-03-14T09:26:53.000Z,buildbox,12.35,,"; got != want {
+		t.Errorf("got the CSV row %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pressureHistoryLength is how many ticks of history the Pressure tab keeps.
+const pressureHistoryLength = 60
+
+// pressureResources are the files of /proc/pressure, in display order.
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// PressureLine is one line of a PSI file: the share of time, in percent,
+// during which some (or all, for "full") tasks were stalled waiting for the
+// resource, averaged over 10s, 60s and 300s.
+type PressureLine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64 // in microseconds
+}
+
+// PressureStats is the content of one /proc/pressure file. Older kernels
+// have no "full" line for the CPU.
+type PressureStats struct {
+	Some    PressureLine
+	Full    PressureLine
+	HasFull bool
+}
+
+// PressureSample holds the Pressure Stall Information of each resource, by
+// name ("cpu", "memory", "io").
+type PressureSample map[string]PressureStats
+
+// parsePressure reads the content of a /proc/pressure file:
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(r io.Reader) (PressureStats, error) {
+	var stats PressureStats
+	var hasSome bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var line PressureLine
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				return stats, fmt.Errorf("invalid field %q", field)
+			}
+			var err error
+			switch key {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return stats, fmt.Errorf("invalid field %q", field)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			stats.Some = line
+			hasSome = true
+		case "full":
+			stats.Full = line
+			stats.HasFull = true
+		default:
+			return stats, fmt.Errorf("unknown line %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	if !hasSome {
+		return stats, fmt.Errorf("no \"some\" line")
+	}
+	return stats, nil
+}
+
+// readPressure reads the PSI files of dir (normally /proc/pressure). It
+// returns nil when they can't be read: PSI needs Linux 4.20 or newer, built
+// with it and not disabled with psi=0.
+func readPressure(dir string) PressureSample {
+	pressure := make(PressureSample)
+	for _, resource := range pressureResources {
+		f, err := os.Open(filepath.Join(dir, resource))
+		if err != nil {
+			continue
+		}
+		stats, err := parsePressure(f)
+		f.Close()
+		if err == nil {
+			pressure[resource] = stats
+		}
+	}
+	if len(pressure) == 0 {
+		return nil
+	}
+	return pressure
+}
+
+// pressureAvailable reports whether the kernel exposes PSI, to decide once at
+// startup if the Pressure tab is shown.
+func pressureAvailable(dir string) bool {
+	return readPressure(dir) != nil
+}
+
+// updatePressureHistory appends the "some" avg10 of each resource to its
+// history, dropping the oldest values past pressureHistoryLength.
+func updatePressureHistory(history map[string][]float64, pressure PressureSample) {
+	for resource, stats := range pressure {
+		values := append(history[resource], stats.Some.Avg10)
+		if len(values) > pressureHistoryLength {
+			values = values[len(values)-pressureHistoryLength:]
+		}
+		history[resource] = values
+	}
+}
+
+// sparkline draws percentages as a row of block heights, colored like bars.
+func sparkline(theme *Theme, values []float64) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	var text string
+	for _, value := range values {
+		level := int(value / 100 * float64(len(levels)-1))
+		if level < 0 {
+			level = 0
+		} else if level >= len(levels) {
+			level = len(levels) - 1
+		}
+		colorCode := barColor(theme, value)
+		text += colorCode + string(levels[level])
+	}
+	if text != "" {
+		text += "[-]"
+	}
+	return text
+}
+
+// renderPressure shows, for each resource, a bar and the averages of its
+// "some" and "full" lines, and the history of the 10s "some" average.
+func renderPressure(theme *Theme, pressure PressureSample, history map[string][]float64, width int) string {
+	if pressure == nil {
+		return tr("Pressure Stall Information is not available.")
+	}
+	var text string
+	for _, resource := range pressureResources {
+		stats, ok := pressure[resource]
+		if !ok {
+			continue
+		}
+		text += fmt.Sprintf("[::b]%s[::-]\n", strings.ToUpper(resource))
+		text += pressureLineText(theme, "some", stats.Some, width)
+		if stats.HasFull {
+			text += pressureLineText(theme, "full", stats.Full, width)
+		}
+		text += fmt.Sprintf("  "+tr("history")+" %s\n\n", sparkline(theme, history[resource]))
+	}
+	return text
+}
+
+func pressureLineText(theme *Theme, name string, line PressureLine, width int) string {
+	pressureBar, colorCode := createBar(theme, line.Avg10, userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+	return fmt.Sprintf("  %s %s avg10 %s%s[-]  avg60 %s  avg300 %s\n", name, pressureBar, colorCode, formatPercent(line.Avg10), formatPercent(line.Avg60), formatPercent(line.Avg300))
+}
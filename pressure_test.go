@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParsePressure(t *testing.T) {
+	for _, test := range []struct {
+		file string
+		want PressureStats
+		err  string
+	}{
+		{"psi/cpu", PressureStats{Some: PressureLine{Avg10: 12.5, Avg60: 8.2, Avg300: 3.1, Total: 123456789}, HasFull: true}, ""},
+		{"psi/io", PressureStats{
+			Some:    PressureLine{Avg10: 35, Avg60: 20.4, Avg300: 9.8, Total: 987654},
+			Full:    PressureLine{Avg10: 28, Avg60: 15, Avg300: 7.5, Total: 765432},
+			HasFull: true,
+		}, ""},
+		// Kernels older than 5.13 have no "full" line for the CPU.
+		{"no-cpu-full/cpu", PressureStats{Some: PressureLine{Avg10: 1.25, Avg60: 0.75, Avg300: 0.3, Total: 55555}}, ""},
+		{"malformed/memory", PressureStats{}, `invalid field "avg10=0.5x"`},
+		{"malformed/io", PressureStats{}, `invalid field "avg10"`},
+	} {
+		t.Run(test.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "pressure", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := parsePressure(f)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got  %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePressureLines(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		err     string
+	}{
+		{"", `no "some" line`},
+		{"full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n", `no "some" line`},
+		{"some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nhalf avg10=0.00\n", `unknown line "half"`},
+		{"some avg10=0.00 avg60=0.00 avg300=0.00 total=-1\n", `invalid field "total=-1"`},
+		{"\nsome avg10=0.00 avg60=0.00 avg300=0.00 total=0 extra=1\n\n", ""},
+	} {
+		_, err := parsePressure(strings.NewReader(test.content))
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("parsePressure(%q): got error %v, want %q", test.content, err, test.err)
+		}
+	}
+}
+
+func TestReadPressure(t *testing.T) {
+	for _, test := range []struct {
+		dir       string
+		resources []string
+		hasFull   []string
+	}{
+		{"psi", []string{"cpu", "memory", "io"}, []string{"cpu", "memory", "io"}},
+		{"no-cpu-full", []string{"cpu", "memory", "io"}, []string{"memory", "io"}},
+		// A file that can't be parsed is left out, not the whole sample.
+		{"malformed", []string{"cpu"}, nil},
+		// No /proc/pressure: the kernel has no PSI.
+		{"missing", nil, nil},
+	} {
+		t.Run(test.dir, func(t *testing.T) {
+			dir := filepath.Join("testdata", "pressure", test.dir)
+			pressure := readPressure(dir)
+			if len(test.resources) == 0 {
+				if pressure != nil || pressureAvailable(dir) {
+					t.Errorf("got %v, want nil", pressure)
+				}
+				return
+			}
+			if len(pressure) != len(test.resources) || !pressureAvailable(dir) {
+				t.Errorf("got %v, want %v", pressure, test.resources)
+			}
+			for _, resource := range test.resources {
+				stats, ok := pressure[resource]
+				if !ok {
+					t.Errorf("%s missing", resource)
+					continue
+				}
+				if wantFull := slices.Contains(test.hasFull, resource); stats.HasFull != wantFull {
+					t.Errorf("%s: HasFull %v, want %v", resource, stats.HasFull, wantFull)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshInterval = 1 * time.Second
+	minRefreshInterval     = 250 * time.Millisecond
+	maxRefreshInterval     = 60 * time.Second
+)
+
+// refreshSteps are the intervals the '+' and '-' keys move between.
+var refreshSteps = []time.Duration{
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	3 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+}
+
+// refreshControl holds the refresh interval and the paused state. It is shared
+// between the key handler and the collection goroutine, which gets a signal on
+// changed whenever one of them is modified.
+type refreshControl struct {
+	mu       sync.Mutex
+	interval time.Duration
+	paused   bool
+	changed  chan struct{}
+}
+
+func newRefreshControl(interval time.Duration) *refreshControl {
+	return &refreshControl{
+		interval: clampInterval(interval),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+func clampInterval(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return defaultRefreshInterval
+	}
+	if interval < minRefreshInterval {
+		return minRefreshInterval
+	}
+	if interval > maxRefreshInterval {
+		return maxRefreshInterval
+	}
+	return interval
+}
+
+func (r *refreshControl) State() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.interval, r.paused
+}
+
+func (r *refreshControl) TogglePause() {
+	r.mu.Lock()
+	r.paused = !r.paused
+	r.mu.Unlock()
+	r.notify()
+}
+
+// Slower moves to the next longer step, Faster to the next shorter one.
+func (r *refreshControl) Slower() {
+	r.mu.Lock()
+	for _, step := range refreshSteps {
+		if step > r.interval {
+			r.interval = step
+			break
+		}
+	}
+	r.mu.Unlock()
+	r.notify()
+}
+
+func (r *refreshControl) Faster() {
+	r.mu.Lock()
+	for i := len(refreshSteps) - 1; i >= 0; i-- {
+		if refreshSteps[i] < r.interval {
+			r.interval = refreshSteps[i]
+			break
+		}
+	}
+	r.mu.Unlock()
+	r.notify()
+}
+
+func (r *refreshControl) notify() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+// maxProcesses is how many processes, busiest first, a sample keeps.
+const maxProcesses = 100
+
+// Sample holds everything measured during one refresh tick. It is filled by
+// the collector and then used both to draw the panels and to evaluate the
+// alert rules, so both always see the same numbers.
+type Sample struct {
+	Time   time.Time
+	Uptime uint64
+
+	CPUTotal   float64
+	CPUPerCore []float64
+	CPUTimes   CPUTimesSample
+
+	Load1  float64
+	Load5  float64
+	Load15 float64
+
+	MemTotal       uint64
+	MemUsed        uint64
+	MemUsedPercent float64
+	MemAvailable   uint64
+	MemFree        uint64
+	MemBuffers     uint64
+	MemCached      uint64
+	MemShared      uint64
+	MemDirty       uint64
+	MemWriteBack   uint64
+
+	SwapTotal       uint64
+	SwapUsed        uint64
+	SwapUsedPercent float64
+	SwapInRate      float64
+	SwapOutRate     float64
+
+	Disks     []DiskSample
+	DiskIO    []DiskIOSample
+	Temps     []TempSample
+	Network   []NetSample
+	Processes []ProcessSample
+	Sessions  []SessionSample
+
+	Cgroup   *CgroupSample
+	Pressure PressureSample
+
+	// Unavailable is why each probe that failed measured nothing.
+	Unavailable probeErrors
+}
+
+// CPUTimesSample is how the CPU time was spent since the previous tick, in
+// percent of the total.
+type CPUTimesSample struct {
+	User    float64
+	System  float64
+	Iowait  float64
+	Irq     float64
+	Softirq float64
+	Steal   float64
+	Nice    float64
+	Idle    float64
+}
+
+type DiskSample struct {
+	Mountpoint  string
+	Device      string
+	Fstype      string
+	Opts        []string
+	ReadOnly    bool
+	Total       uint64
+	Used        uint64
+	UsedPercent float64
+
+	InodesTotal       uint64
+	InodesUsed        uint64
+	InodesUsedPercent float64
+}
+
+type DiskIOSample struct {
+	Name       string
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadRate   float64
+	WriteRate  float64
+}
+
+type TempSample struct {
+	SensorKey   string
+	Temperature float64
+	High        float64
+	Critical    float64
+}
+
+type NetSample struct {
+	Name      string
+	Addrs     []string
+	Up        bool
+	BytesRecv uint64
+	BytesSent uint64
+	RecvRate  float64
+	SendRate  float64
+	Errors    uint64
+	Drops     uint64
+}
+
+type ProcessSample struct {
+	PID        int32
+	Name       string
+	User       string
+	Status     string
+	CPUPercent float64
+	MemPercent float32
+	RSS        uint64
+	Threads    int32
+	Command    string
+}
+
+// collector gathers the samples. It keeps what the previous tick measured so
+// that rates (network, disk IO) and per-process CPU usage can be computed
+// from the difference between two ticks.
+type collector struct {
+	lastTime   time.Time
+	lastNet    map[string]net.IOCountersStat
+	lastDiskIO map[string]disk.IOCountersStat
+	lastSwap   *mem.SwapMemoryStat
+	lastTimes  *cpu.TimesStat
+	lastCgroup *CgroupSample
+	processes  map[int32]*process.Process
+}
+
+func newCollector() *collector {
+	return &collector{
+		lastNet:    make(map[string]net.IOCountersStat),
+		lastDiskIO: make(map[string]disk.IOCountersStat),
+		processes:  make(map[int32]*process.Process),
+	}
+}
+
+// probeErrors holds why probes failed, by probe name: "cpu", "memory",
+// "disks"... The panels show the reason instead of what the probe measures.
+type probeErrors map[string]string
+
+// run runs one probe. Its error, or its panic (e.g. a library indexing an
+// empty list on an unusual system), is recorded instead of stopping the
+// collection.
+func (probes probeErrors) run(name string, probe func() error) {
+	defer func() {
+		if p := recover(); p != nil {
+			probes[name] = fmt.Sprint(p)
+		}
+	}()
+	if err := probe(); err != nil {
+		probes[name] = err.Error()
+	}
+}
+
+// Collect measures everything for one tick. A probe that fails leaves its
+// part of the sample empty and its reason in Unavailable, so it never
+// returns an error.
+func (c *collector) Collect() (*Sample, error) {
+	sample := &Sample{Time: time.Now(), Unavailable: make(probeErrors)}
+	probes := sample.Unavailable
+	var elapsed float64
+	if !c.lastTime.IsZero() {
+		elapsed = sample.Time.Sub(c.lastTime).Seconds()
+	}
+	c.lastTime = sample.Time
+
+	probes.run("uptime", func() (err error) {
+		sample.Uptime, err = host.Uptime()
+		return err
+	})
+
+	//CPU
+	probes.run("cpu", func() error {
+		globalCpuUse, err := cpu.Percent(0, false)
+		if err != nil {
+			return err
+		}
+		if len(globalCpuUse) > 0 {
+			sample.CPUTotal = globalCpuUse[0]
+		}
+		sample.CPUPerCore, err = cpu.Percent(0, true)
+		if err != nil {
+			return err
+		}
+		times, err := cpu.Times(false)
+		if len(times) > 0 {
+			if c.lastTimes != nil {
+				sample.CPUTimes = cpuTimesPercent(*c.lastTimes, times[0])
+			}
+			c.lastTimes = &times[0]
+		}
+		return err
+	})
+	probes.run("load", func() error {
+		loadAvg, err := load.Avg()
+		if err != nil {
+			return err
+		}
+		sample.Load1 = loadAvg.Load1
+		sample.Load5 = loadAvg.Load5
+		sample.Load15 = loadAvg.Load15
+		return nil
+	})
+
+	//Cgroup
+	probes.run("cgroup", func() error {
+		sample.Cgroup = readCgroup("/")
+		if sample.Cgroup != nil {
+			if c.lastCgroup != nil && elapsed > 0 && sample.Cgroup.CPUQuota > 0 {
+				usage := rate(c.lastCgroup.CPUUsage, sample.Cgroup.CPUUsage, elapsed) / 1e6
+				sample.Cgroup.CPUPercent = usage / sample.Cgroup.CPUQuota * 100
+			}
+			c.lastCgroup = sample.Cgroup
+		}
+		return nil
+	})
+
+	//Pressure
+	probes.run("pressure", func() error {
+		sample.Pressure = readPressure("/proc/pressure")
+		return nil
+	})
+
+	//Memory
+	probes.run("memory", func() error {
+		v, err := mem.VirtualMemory()
+		if err != nil {
+			return err
+		}
+		sample.MemTotal = v.Total
+		sample.MemUsed = v.Used
+		sample.MemUsedPercent = v.UsedPercent
+		sample.MemAvailable = v.Available
+		sample.MemFree = v.Free
+		sample.MemBuffers = v.Buffers
+		sample.MemCached = v.Cached
+		sample.MemShared = v.Shared
+		sample.MemDirty = v.Dirty
+		sample.MemWriteBack = v.WriteBack
+		return nil
+	})
+	probes.run("swap", func() error {
+		swap, err := mem.SwapMemory()
+		if err != nil {
+			return err
+		}
+		sample.SwapTotal = swap.Total
+		sample.SwapUsed = swap.Used
+		sample.SwapUsedPercent = swap.UsedPercent
+		if c.lastSwap != nil && elapsed > 0 {
+			sample.SwapInRate = rate(c.lastSwap.Sin, swap.Sin, elapsed)
+			sample.SwapOutRate = rate(c.lastSwap.Sout, swap.Sout, elapsed)
+		}
+		c.lastSwap = swap
+		return nil
+	})
+
+	//Disk
+	probes.run("disks", func() error {
+		partitions, err := disk.Partitions(false)
+		if err != nil {
+			return err
+		}
+		for i := range partitions {
+			if !userPrefs.Disks.shown(partitions[i].Fstype, partitions[i].Mountpoint) {
+				continue
+			}
+			usage, err := disk.Usage(partitions[i].Mountpoint)
+			if err != nil {
+				continue
+			}
+			sample.Disks = append(sample.Disks, DiskSample{
+				Mountpoint:  usage.Path,
+				Device:      partitions[i].Device,
+				Fstype:      partitions[i].Fstype,
+				Opts:        partitions[i].Opts,
+				ReadOnly:    containsString(partitions[i].Opts, "ro"),
+				Total:       usage.Total,
+				Used:        usage.Used,
+				UsedPercent: usage.UsedPercent,
+
+				InodesTotal:       usage.InodesTotal,
+				InodesUsed:        usage.InodesUsed,
+				InodesUsedPercent: usage.InodesUsedPercent,
+			})
+		}
+		return nil
+	})
+	probes.run("diskio", func() error {
+		ioCounters, err := disk.IOCounters()
+		if err != nil {
+			return err
+		}
+		for name, counters := range ioCounters {
+			ioSample := DiskIOSample{Name: name, ReadBytes: counters.ReadBytes, WriteBytes: counters.WriteBytes}
+			if last, ok := c.lastDiskIO[name]; ok && elapsed > 0 {
+				ioSample.ReadRate = rate(last.ReadBytes, counters.ReadBytes, elapsed)
+				ioSample.WriteRate = rate(last.WriteBytes, counters.WriteBytes, elapsed)
+			}
+			sample.DiskIO = append(sample.DiskIO, ioSample)
+		}
+		c.lastDiskIO = ioCounters
+		sort.Slice(sample.DiskIO, func(i, j int) bool { return sample.DiskIO[i].Name < sample.DiskIO[j].Name })
+		return nil
+	})
+
+	//Temperature
+	probes.run("temps", func() error {
+		temperatures, err := sensors.SensorsTemperatures()
+		if err != nil && len(temperatures) == 0 {
+			return err
+		}
+		// With some temperatures, the error only lists the sensors that
+		// couldn't be read.
+		keys := make(map[string]int)
+		for i := range temperatures {
+			// Sensors without a label all get their chip name as key, number
+			// the duplicates so that each one keeps its own min/max.
+			key := temperatures[i].SensorKey
+			keys[key]++
+			if keys[key] > 1 {
+				key = fmt.Sprintf("%s_%d", key, keys[key])
+			}
+			sample.Temps = append(sample.Temps, TempSample{
+				SensorKey:   key,
+				Temperature: temperatures[i].Temperature,
+				High:        temperatures[i].High,
+				Critical:    temperatures[i].Critical,
+			})
+		}
+		return nil
+	})
+
+	//Network
+	probes.run("network", func() error {
+		netCounters, err := net.IOCounters(true)
+		if err != nil {
+			return err
+		}
+		// Without the interfaces, the counters are still shown, as down and
+		// without addresses.
+		interfaces, _ := net.Interfaces()
+		lastNet := make(map[string]net.IOCountersStat)
+		for _, counters := range netCounters {
+			netSample := NetSample{
+				Name:      counters.Name,
+				BytesRecv: counters.BytesRecv,
+				BytesSent: counters.BytesSent,
+				Errors:    counters.Errin + counters.Errout,
+				Drops:     counters.Dropin + counters.Dropout,
+			}
+			if last, ok := c.lastNet[counters.Name]; ok && elapsed > 0 {
+				netSample.RecvRate = rate(last.BytesRecv, counters.BytesRecv, elapsed)
+				netSample.SendRate = rate(last.BytesSent, counters.BytesSent, elapsed)
+			}
+			for _, iface := range interfaces {
+				if iface.Name != counters.Name {
+					continue
+				}
+				for _, flag := range iface.Flags {
+					if flag == "up" {
+						netSample.Up = true
+					}
+				}
+				for _, addr := range iface.Addrs {
+					netSample.Addrs = append(netSample.Addrs, addr.Addr)
+				}
+			}
+			lastNet[counters.Name] = counters
+			sample.Network = append(sample.Network, netSample)
+		}
+		c.lastNet = lastNet
+		return nil
+	})
+
+	//Processes
+	probes.run("processes", func() (err error) {
+		sample.Processes, err = c.collectProcesses()
+		return err
+	})
+
+	//Sessions
+	probes.run("sessions", func() (err error) {
+		sample.Sessions, err = collectSessions(sample.Time)
+		return err
+	})
+	return sample, nil
+}
+
+// staticSource is where collectStaticInfo reads the description of the
+// machine from: gopsutil (hostStatic), or a fake in the tests.
+type staticSource interface {
+	PlatformInformation() (platform, family, version string, err error)
+	CPUCounts(logical bool) (int, error)
+	CPUInfo() ([]cpu.InfoStat, error)
+	HostInfo() (*host.InfoStat, error)
+	KernelVersion() (string, error)
+	KernelArch() (string, error)
+}
+
+// hostStatic describes this machine.
+type hostStatic struct{}
+
+func (hostStatic) PlatformInformation() (string, string, string, error) {
+	return host.PlatformInformation()
+}
+
+func (hostStatic) CPUCounts(logical bool) (int, error) {
+	return cpu.Counts(logical)
+}
+
+func (hostStatic) CPUInfo() ([]cpu.InfoStat, error) {
+	return cpu.Info()
+}
+
+func (hostStatic) HostInfo() (*host.InfoStat, error) {
+	return host.Info()
+}
+
+func (hostStatic) KernelVersion() (string, error) {
+	return host.KernelVersion()
+}
+
+func (hostStatic) KernelArch() (string, error) {
+	return host.KernelArch()
+}
+
+// collectStaticInfo gathers what doesn't change while TermiDash runs: the OS,
+// the CPU model and the user's environment. What can't be read is left empty,
+// with its reason in Unavailable.
+func collectStaticInfo() StaticInfo {
+	return readStaticInfo(hostStatic{})
+}
+
+// readStaticInfo is collectStaticInfo with the probes of source. The logo,
+// the user's environment and PSI are still read from this machine.
+func readStaticInfo(source staticSource) StaticInfo {
+	staticInfo := StaticInfo{Unavailable: make(probeErrors)}
+	probes := staticInfo.Unavailable
+	var staticPlatform string
+	probes.run("platform", func() (err error) {
+		staticPlatform, staticInfo.OSFamily, staticInfo.OSVersion, err = source.PlatformInformation()
+		if staticPlatform != "" {
+			staticInfo.OS = strings.ToUpper(staticPlatform[:1]) + staticPlatform[1:]
+		}
+		return err
+	})
+	staticInfo.Logo = detectLogo(userPrefs.Logo, staticPlatform, staticInfo.OSFamily, staticInfo.OSVersion)
+	probes.run("cores", func() (err error) {
+		var errPhysical error
+		staticInfo.CPUPhysCore, errPhysical = source.CPUCounts(false)
+		staticInfo.CPULogCore, err = source.CPUCounts(true)
+		return cmp.Or(err, errPhysical)
+	})
+	probes.run("cpu", func() error {
+		cpuInfo, err := source.CPUInfo()
+		if len(cpuInfo) == 0 {
+			if err == nil {
+				err = errors.New("no CPU information")
+			}
+			return err
+		}
+		staticInfo.CPUModel = cpuInfo[0].ModelName
+		staticInfo.CPUVendor = cpuInfo[0].VendorID
+		staticInfo.CPUFamily = cpuInfo[0].Family
+		staticInfo.CPUMHz = cpuInfo[0].Mhz
+		staticInfo.CPUCacheSize = cpuInfo[0].CacheSize
+		return nil
+	})
+	probes.run("host", func() error {
+		hostInfo, err := source.HostInfo()
+		if hostInfo == nil {
+			return err
+		}
+		staticInfo.Hostname = hostInfo.Hostname
+		staticInfo.Virtualization = hostInfo.VirtualizationSystem
+		staticInfo.VirtualizationRole = hostInfo.VirtualizationRole
+		staticInfo.BootTime = hostInfo.BootTime
+		return err
+	})
+	probes.run("kernel", func() (err error) {
+		staticInfo.KernelVersion, err = source.KernelVersion()
+		if err != nil {
+			return err
+		}
+		staticInfo.KernelArch, err = source.KernelArch()
+		return err
+	})
+	staticInfo.Pressure = pressureAvailable("/proc/pressure")
+	detectEnvironment(&staticInfo)
+	return staticInfo
+}
+
+func (c *collector) collectProcesses() ([]ProcessSample, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int32]*process.Process, len(pids))
+	var processes []ProcessSample
+	for _, pid := range pids {
+		proc, ok := c.processes[pid]
+		if !ok {
+			var err error
+			proc, err = process.NewProcess(pid)
+			if err != nil {
+				continue
+			}
+		}
+		seen[pid] = proc
+		// Percent measures the CPU used since its previous call on the same
+		// Process, which is why they are kept between ticks.
+		cpuPercent, err := proc.Percent(0)
+		if err != nil {
+			continue
+		}
+		procSample := ProcessSample{PID: pid, CPUPercent: cpuPercent}
+		procSample.Name, _ = proc.Name()
+		procSample.User, _ = proc.Username()
+		procSample.MemPercent, _ = proc.MemoryPercent()
+		procSample.Threads, _ = proc.NumThreads()
+		if memInfo, err := proc.MemoryInfo(); err == nil {
+			procSample.RSS = memInfo.RSS
+		}
+		if status, err := proc.Status(); err == nil && len(status) > 0 {
+			procSample.Status = status[0]
+		}
+		procSample.Command, _ = proc.Cmdline()
+		if procSample.Command == "" {
+			procSample.Command = procSample.Name
+		}
+		processes = append(processes, procSample)
+	}
+	c.processes = seen
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].CPUPercent != processes[j].CPUPercent {
+			return processes[i].CPUPercent > processes[j].CPUPercent
+		}
+		return processes[i].MemPercent > processes[j].MemPercent
+	})
+	if len(processes) > maxProcesses {
+		processes = processes[:maxProcesses]
+	}
+	return processes, nil
+}
+
+// cpuTimesPercent turns two cpu.Times readings into the share of each kind
+// of CPU time between them. Guest time is already counted in user time on
+// Linux, so it is left out of the total.
+func cpuTimesPercent(last, current cpu.TimesStat) CPUTimesSample {
+	delta := func(last, current float64) float64 {
+		if current < last {
+			return 0
+		}
+		return current - last
+	}
+	user := delta(last.User, current.User)
+	system := delta(last.System, current.System)
+	iowait := delta(last.Iowait, current.Iowait)
+	irq := delta(last.Irq, current.Irq)
+	softirq := delta(last.Softirq, current.Softirq)
+	steal := delta(last.Steal, current.Steal)
+	nice := delta(last.Nice, current.Nice)
+	idle := delta(last.Idle, current.Idle)
+	total := user + system + iowait + irq + softirq + steal + nice + idle
+	if total <= 0 {
+		return CPUTimesSample{}
+	}
+	return CPUTimesSample{
+		User:    user / total * 100,
+		System:  system / total * 100,
+		Iowait:  iowait / total * 100,
+		Irq:     irq / total * 100,
+		Softirq: softirq / total * 100,
+		Steal:   steal / total * 100,
+		Nice:    nice / total * 100,
+		Idle:    idle / total * 100,
+	}
+}
+
+// rate returns how fast a counter went from last to current, per second. A
+// counter that went backwards (e.g. an interface that was reset) gives 0.
+func rate(last, current uint64, seconds float64) float64 {
+	if current < last || seconds <= 0 {
+		return 0
+	}
+	return float64(current-last) / seconds
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProbeErrors(t *testing.T) {
+	probes := make(probeErrors)
+	var ran []string
+	probes.run("cpu", func() error {
+		ran = append(ran, "cpu")
+		return nil
+	})
+	probes.run("memory", func() error {
+		ran = append(ran, "memory")
+		return errors.New("open /proc/meminfo: no such file or directory")
+	})
+	probes.run("temps", func() error {
+		ran = append(ran, "temps")
+		var sensors []string
+		_ = sensors[0]
+		return nil
+	})
+	probes.run("disks", func() error {
+		ran = append(ran, "disks")
+		return nil
+	})
+
+	if len(ran) != 4 {
+		t.Errorf("ran %v, want every probe to run", ran)
+	}
+	want := probeErrors{
+		"memory": "open /proc/meminfo: no such file or directory",
+		"temps":  "runtime error: index out of range [0] with length 0",
+	}
+	if len(probes) != len(want) {
+		t.Errorf("got %v, want %v", probes, want)
+	}
+	for name, reason := range want {
+		if probes[name] != reason {
+			t.Errorf("probe %s: got %q, want %q", name, probes[name], reason)
+		}
+	}
+}
+
+func TestReadStaticInfoUnavailable(t *testing.T) {
+	denied := errors.New("permission denied")
+	for _, test := range []struct {
+		name        string
+		source      fakeStaticSource
+		unavailable probeErrors
+	}{
+		{"every probe", fakeStaticSource{}, probeErrors{}},
+		{"platform", fakeStaticSource{failures: map[string]error{"PlatformInformation": denied}}, probeErrors{"platform": "permission denied"}},
+		// The physical cores can't be counted on some platforms, the logical
+		// ones still are.
+		{"physical cores", fakeStaticSource{failures: map[string]error{"CPUCounts": denied}}, probeErrors{"cores": "permission denied"}},
+		{"logical cores", fakeStaticSource{failures: map[string]error{"CPUCounts logical": denied}}, probeErrors{"cores": "permission denied"}},
+		{"cpu", fakeStaticSource{failures: map[string]error{"CPUInfo": denied}}, probeErrors{"cpu": "permission denied"}},
+		{"host", fakeStaticSource{failures: map[string]error{"HostInfo": denied}}, probeErrors{"host": "permission denied"}},
+		{"kernel arch", fakeStaticSource{failures: map[string]error{"KernelArch": denied}}, probeErrors{"kernel": "permission denied"}},
+		{"panics", fakeStaticSource{failures: map[string]error{"CPUInfo": denied, "KernelVersion": denied}, panics: true},
+			probeErrors{"cpu": "permission denied", "kernel": "permission denied"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			staticInfo := readStaticInfo(test.source)
+			if len(staticInfo.Unavailable) != len(test.unavailable) {
+				t.Errorf("Unavailable %v, want %v", staticInfo.Unavailable, test.unavailable)
+			}
+			for name, reason := range test.unavailable {
+				if staticInfo.Unavailable[name] != reason {
+					t.Errorf("probe %s: got %q, want %q", name, staticInfo.Unavailable[name], reason)
+				}
+			}
+			// What the other probes read is kept.
+			if test.unavailable["cpu"] == "" && staticInfo.CPUModel != "AMD Ryzen 7 5800U" {
+				t.Errorf("CPU model %q", staticInfo.CPUModel)
+			}
+			if test.unavailable["cores"] == "" && (staticInfo.CPUPhysCore != 4 || staticInfo.CPULogCore != 8) {
+				t.Errorf("cores %d/%d, want 4/8", staticInfo.CPUPhysCore, staticInfo.CPULogCore)
+			}
+			if test.unavailable["host"] == "" && staticInfo.Hostname != "buildbox" {
+				t.Errorf("hostname %q", staticInfo.Hostname)
+			}
+			if test.unavailable["platform"] == "" && staticInfo.OS != "Debian" {
+				t.Errorf("OS %q", staticInfo.OS)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// SensorSettings is the [Sensors] table of the config file:
+//
+//	[Sensors]
+//	Hide = ["acpitz*", "iwlwifi_1_*"]
+//	[Sensors.Rename]
+//	coretemp_package_id_0 = "CPU"
+//
+// Hide takes glob patterns matched against the sensor keys, and Rename maps a
+// sensor key to the label shown for it.
+type SensorSettings struct {
+	Hide   []string          `toml:"Hide,omitempty"`
+	Rename map[string]string `toml:"Rename,omitempty"`
+}
+
+// tempRange is the lowest and highest temperature a sensor reported since
+// TermiDash started.
+type tempRange struct {
+	Min float64
+	Max float64
+}
+
+func (settings SensorSettings) hidden(key string) bool {
+	for _, pattern := range settings.Hide {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sensorChip splits a gopsutil sensor key, which is the chip name and the
+// sensor label joined by an underscore ("coretemp_core_0"), into the chip and
+// a readable label ("core 0").
+func sensorChip(key string) (string, string) {
+	chip, label, found := strings.Cut(key, "_")
+	if !found {
+		return key, key
+	}
+	return chip, strings.ReplaceAll(label, "_", " ")
+}
+
+// updateTempRanges records the new readings in the per-sensor min/max.
+func updateTempRanges(ranges map[string]tempRange, temps []TempSample) {
+	for _, temp := range temps {
+		seen, ok := ranges[temp.SensorKey]
+		if !ok {
+			ranges[temp.SensorKey] = tempRange{Min: temp.Temperature, Max: temp.Temperature}
+			continue
+		}
+		if temp.Temperature < seen.Min {
+			seen.Min = temp.Temperature
+		}
+		if temp.Temperature > seen.Max {
+			seen.Max = temp.Temperature
+		}
+		ranges[temp.SensorKey] = seen
+	}
+}
+
+// tempPercent is how close a sensor is to its critical temperature, or to
+// its high one when there is no critical value, or to 100C when the chip
+// reports neither. It is used to size and color the bar.
+func tempPercent(temp TempSample) float64 {
+	limit := 100.0
+	if temp.Critical > 0 {
+		limit = temp.Critical
+	} else if temp.High > 0 {
+		limit = temp.High
+	}
+	percent := temp.Temperature / limit * 100
+	if percent > 100 {
+		percent = 100
+	}
+	if percent < 0 {
+		percent = 0
+	}
+	return percent
+}
+
+// renderTemperatures lists the sensors grouped by chip, each with a bar. With
+// details, the high/critical values and the session min/max are added.
+func renderTemperatures(theme *Theme, temps []TempSample, ranges map[string]tempRange, settings SensorSettings, details bool, width int) string {
+	groups := make(map[string][]TempSample)
+	var chips []string
+	for _, temp := range temps {
+		if settings.hidden(temp.SensorKey) {
+			continue
+		}
+		chip, _ := sensorChip(temp.SensorKey)
+		if _, ok := groups[chip]; !ok {
+			chips = append(chips, chip)
+		}
+		groups[chip] = append(groups[chip], temp)
+	}
+	if len(chips) == 0 {
+		return tr("No temperature sensors found.")
+	}
+	sort.Strings(chips)
+
+	var text string
+	for _, chip := range chips {
+		text += fmt.Sprintf("[::b]%s[::-]\n", tview.Escape(chip))
+		for _, temp := range groups[chip] {
+			_, label := sensorChip(temp.SensorKey)
+			if renamed, ok := settings.Rename[temp.SensorKey]; ok {
+				label = renamed
+			}
+			tempBar, colorCode := createBar(theme, tempPercent(temp), userPrefs.BarFilledChar, userPrefs.BarEmptyChar, width)
+			text += fmt.Sprintf("  %s %s %s%s[-]", tempBar, tview.Escape(label), colorCode, formatTemp(temp.Temperature))
+			if details {
+				seen := ranges[temp.SensorKey]
+				text += fmt.Sprintf(" ("+tr("min %s, max %s"), formatTemp(seen.Min), formatTemp(seen.Max))
+				if temp.High > 0 {
+					text += fmt.Sprintf(", "+tr("high %s"), formatTemp(temp.High))
+				}
+				if temp.Critical > 0 {
+					text += fmt.Sprintf(", "+tr("critical %s"), formatTemp(temp.Critical))
+				}
+				text += ")"
+			}
+			text += "\n"
+		}
+	}
+	return text
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestRenderTemperaturesEscaped(t *testing.T) {
+	useDefaultPreferences(t)
+	temps := []TempSample{{SensorKey: "coretemp_package_id_0", Temperature: 55}, {SensorKey: "[chip]_core_0", Temperature: 50}}
+	settings := SensorSettings{Rename: map[string]string{"coretemp_package_id_0": "CPU [pkg]"}}
+	text := renderTemperatures(&defaultTheme, temps, map[string]tempRange{}, settings, false, 10)
+	shown := tview.NewTextView().SetDynamicColors(true).SetText(text).GetText(true)
+	for _, want := range []string{"CPU [pkg] 55", "[chip]\n"} {
+		if !strings.Contains(shown, want) {
+			t.Errorf("%q not in the panel:\n%s", want, shown)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// newLoginWindow is how long a remote login stays highlighted.
+const newLoginWindow = 10 * time.Minute
+
+// SessionSample is a login session, as listed in utmp.
+type SessionSample struct {
+	User     string
+	Terminal string
+	Host     string
+	Started  time.Time
+	Idle     time.Duration
+}
+
+// Remote reports whether the session comes from another machine, e.g. SSH.
+func (session SessionSample) Remote() bool {
+	return session.Host != "" && !strings.HasPrefix(session.Host, ":")
+}
+
+func collectSessions(now time.Time) ([]SessionSample, error) {
+	users, err := host.Users()
+	if errors.Is(err, fs.ErrNotExist) {
+		// No utmp file, e.g. in a container: nobody logged in.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []SessionSample
+	for _, user := range users {
+		session := SessionSample{
+			User:     user.User,
+			Terminal: user.Terminal,
+			Host:     user.Host,
+			Started:  time.Unix(int64(user.Started), 0),
+		}
+		// The terminal device is written to whenever something is typed in
+		// it (the echo) or printed, which is close enough to what w(1) calls
+		// idle time.
+		if info, err := os.Stat(filepath.Join("/dev", user.Terminal)); err == nil {
+			session.Idle = now.Sub(info.ModTime())
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.After(sessions[j].Started) })
+	return sessions, nil
+}
+
+// renderSessions lists the login sessions, newest first. Remote logins from
+// the last newLoginWindow are highlighted.
+func renderSessions(theme *Theme, sessions []SessionSample, now time.Time) string {
+	if len(sessions) == 0 {
+		return tr("No login sessions found.")
+	}
+	rows := [][]string{{tr("User"), tr("TTY"), tr("From"), tr("Login"), tr("Idle")}}
+	for _, session := range sessions {
+		from := session.Host
+		if from == "" {
+			from = tr("local")
+		}
+		idle := "-"
+		if session.Idle > 0 {
+			idle = formatIdle(session.Idle)
+		}
+		row := []string{tview.Escape(session.User), tview.Escape(session.Terminal), tview.Escape(from), formatTime(session.Started, false), idle}
+		if session.Remote() && now.Sub(session.Started) < newLoginWindow {
+			colorCode := fmt.Sprintf("[%s::b]", theme.BarYellow.TrueColor().String())
+			for i := range row {
+				row[i] = colorCode + row[i] + "[-::-]"
+			}
+			row[len(row)-1] += " " + tr("new")
+		}
+		rows = append(rows, row)
+	}
+	return fmt.Sprintf(tr("%d sessions, %d remote")+"\n\n%s", len(sessions), countRemote(sessions), alignColumns(rows))
+}
+
+func countRemote(sessions []SessionSample) int {
+	count := 0
+	for _, session := range sessions {
+		if session.Remote() {
+			count++
+		}
+	}
+	return count
+}
+
+// formatIdle shortens an idle time like w(1) does: seconds, minutes, then
+// hours and days.
+func formatIdle(idle time.Duration) string {
+	switch {
+	case idle < time.Minute:
+		return fmt.Sprintf("%ds", int(idle.Seconds()))
+	case idle < time.Hour:
+		return fmt.Sprintf("%dm", int(idle.Minutes()))
+	case idle < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(idle.Hours()), int(idle.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd", int(idle.Hours()/24))
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+)
+
+// sqliteDB reads the table b-trees of a SQLite database file, just enough to
+// count the rows of a table without linking SQLite or running its shell. See
+// https://www.sqlite.org/fileformat.html for the format.
+//
+// The pages written by the transactions committed to the write-ahead log
+// (file-wal), which rpm uses, replace those of the file, as they do for
+// SQLite until they are copied back to the file. A transaction still being
+// written is left out. The shared-memory index of the log isn't read: a
+// database opened while SQLite resets its log may look corrupted, and be
+// read again at the next refresh.
+type sqliteDB struct {
+	f        *os.File
+	pageSize int
+	usable   int // page size minus the bytes reserved at the end of each page
+	pages    int
+
+	wal       *os.File
+	walFrames map[uint32]int64 // offset in wal of the latest committed copy of a page
+}
+
+func openSQLite(file string) (*sqliteDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 100)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:16]) != "SQLite format 3\x00" {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a SQLite database", file)
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	info, err := f.Stat()
+	if err != nil || pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a SQLite database", file)
+	}
+	db := &sqliteDB{
+		f:        f,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+		pages:    int(info.Size() / int64(pageSize)),
+	}
+	if wal, err := os.Open(file + "-wal"); err == nil {
+		db.wal = wal
+		db.readWAL()
+	}
+	return db, nil
+}
+
+func (db *sqliteDB) Close() error {
+	if db.wal != nil {
+		db.wal.Close()
+	}
+	return db.f.Close()
+}
+
+// readWAL indexes the frames of the write-ahead log up to its last commit.
+// A frame holds a copy of a page, and its header the number of pages of the
+// database once its transaction is committed, 0 before the last frame of the
+// transaction. The frames written since the log was last reset are the ones
+// with the salts of its header, and a checksum that follows from the one
+// before.
+func (db *sqliteDB) readWAL() {
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(db.wal, header); err != nil {
+		return
+	}
+	magic := binary.BigEndian.Uint32(header)
+	if (magic != 0x377f0682 && magic != 0x377f0683) || int(binary.BigEndian.Uint32(header[8:])) != db.pageSize {
+		return
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if magic == 0x377f0683 {
+		order = binary.BigEndian
+	}
+	s0, s1 := walChecksum(order, 0, 0, header[:24])
+	if s0 != binary.BigEndian.Uint32(header[24:]) || s1 != binary.BigEndian.Uint32(header[28:]) {
+		return
+	}
+
+	frames := make(map[uint32]int64)
+	frame := make([]byte, 24+db.pageSize)
+	for offset := int64(32); ; offset += int64(len(frame)) {
+		if _, err := db.wal.ReadAt(frame, offset); err != nil {
+			return
+		}
+		if string(frame[8:16]) != string(header[16:24]) {
+			return
+		}
+		s0, s1 = walChecksum(order, s0, s1, frame[:8])
+		s0, s1 = walChecksum(order, s0, s1, frame[24:])
+		if s0 != binary.BigEndian.Uint32(frame[16:]) || s1 != binary.BigEndian.Uint32(frame[20:]) {
+			return
+		}
+		frames[binary.BigEndian.Uint32(frame)] = offset + 24
+		if pages := binary.BigEndian.Uint32(frame[4:]); pages != 0 {
+			db.pages = int(pages)
+			if db.walFrames == nil {
+				db.walFrames = make(map[uint32]int64)
+			}
+			maps.Copy(db.walFrames, frames)
+			clear(frames)
+		}
+	}
+}
+
+// walChecksum continues the checksum s0, s1 of the log over b, made of 32-bit
+// words in the byte order of the log.
+func walChecksum(order binary.ByteOrder, s0, s1 uint32, b []byte) (uint32, uint32) {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// readPage reads a page of the database, from the log if it has a copy.
+func (db *sqliteDB) readPage(page []byte, number uint32) error {
+	if offset, ok := db.walFrames[number]; ok {
+		_, err := db.wal.ReadAt(page, offset)
+		return err
+	}
+	_, err := db.f.ReadAt(page, int64(number-1)*int64(db.pageSize))
+	return err
+}
+
+// countSQLiteRows counts the rows of a table of a SQLite database file.
+func countSQLiteRows(file, table string) (int, error) {
+	db, err := openSQLite(file)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return db.CountRows(table)
+}
+
+// CountRows counts the rows of a table, the way "SELECT count(*)" does.
+func (db *sqliteDB) CountRows(table string) (int, error) {
+	root, err := db.tableRoot(table)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = db.walkTable(root, func(page []byte, header int) error {
+		count += int(binary.BigEndian.Uint16(page[header+3:]))
+		return nil
+	})
+	return count, err
+}
+
+// tableRoot finds the root page of a table in the schema table, whose
+// b-tree starts on page 1 and whose rows are (type, name, tbl_name,
+// rootpage, sql).
+func (db *sqliteDB) tableRoot(table string) (uint32, error) {
+	var root uint32
+	err := db.walkTable(1, func(page []byte, header int) error {
+		cells := int(binary.BigEndian.Uint16(page[header+3:]))
+		for i := range cells {
+			offset := int(binary.BigEndian.Uint16(page[header+8+2*i:]))
+			columns, err := db.leafRecord(page, offset, 4)
+			if err != nil {
+				return err
+			}
+			if len(columns) == 4 && string(columns[0]) == "table" && string(columns[1]) == table {
+				root = uint32(sqliteInt(columns[3]))
+			}
+		}
+		return nil
+	})
+	if err == nil && root == 0 {
+		err = fmt.Errorf("no %s table", table)
+	}
+	return root, err
+}
+
+// walkTable calls leaf with every leaf page of the table b-tree starting at
+// root, and the offset of the page header in it.
+func (db *sqliteDB) walkTable(root uint32, leaf func(page []byte, header int) error) error {
+	pending := []uint32{root}
+	page := make([]byte, db.pageSize)
+	for visited := 0; len(pending) > 0; visited++ {
+		number := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		// A b-tree never holds more pages than the file: more means a loop.
+		if number == 0 || int(number) > db.pages || visited > db.pages {
+			return errors.New("corrupted SQLite b-tree")
+		}
+		if err := db.readPage(page, number); err != nil {
+			return err
+		}
+		header := 0
+		if number == 1 {
+			header = 100
+		}
+		cells := int(binary.BigEndian.Uint16(page[header+3:]))
+		switch page[header] {
+		case 0x0d: // table leaf
+			if header+8+2*cells > db.usable {
+				return errors.New("corrupted SQLite b-tree")
+			}
+			if err := leaf(page, header); err != nil {
+				return err
+			}
+		case 0x05: // table interior: a child page number per cell, and the rightmost child
+			if header+12+2*cells > db.usable {
+				return errors.New("corrupted SQLite b-tree")
+			}
+			pending = append(pending, binary.BigEndian.Uint32(page[header+8:]))
+			for i := range cells {
+				offset := int(binary.BigEndian.Uint16(page[header+12+2*i:]))
+				if offset+4 > db.usable {
+					return errors.New("corrupted SQLite b-tree")
+				}
+				pending = append(pending, binary.BigEndian.Uint32(page[offset:]))
+			}
+		default:
+			return fmt.Errorf("SQLite page %d is not a table page", number)
+		}
+	}
+	return nil
+}
+
+// leafRecord decodes the first columns of the row stored in the cell of a
+// table leaf page at offset, reading the rest of the row from its overflow
+// pages when it doesn't fit on the page.
+func (db *sqliteDB) leafRecord(page []byte, offset, columns int) ([][]byte, error) {
+	payloadSize, n := sqliteVarint(page[min(offset, db.usable):db.usable])
+	if n == 0 || payloadSize > uint64(db.pages*db.pageSize) {
+		return nil, errors.New("corrupted SQLite record")
+	}
+	offset += n
+	if _, n = sqliteVarint(page[min(offset, db.usable):db.usable]); n == 0 { // rowid
+		return nil, errors.New("corrupted SQLite record")
+	}
+	offset += n
+	end := offset + db.localPayload(int(payloadSize))
+	if end > db.usable || (end < offset+int(payloadSize) && end+4 > db.usable) {
+		return nil, errors.New("corrupted SQLite record")
+	}
+	payload := append([]byte(nil), page[offset:end]...)
+	if len(payload) < int(payloadSize) {
+		// The first overflow page follows the part on the page, and each
+		// overflow page starts with the number of the next one.
+		next := binary.BigEndian.Uint32(page[end:])
+		overflow := make([]byte, db.pageSize)
+		for len(payload) < int(payloadSize) {
+			if next == 0 || int(next) > db.pages {
+				return nil, errors.New("corrupted SQLite record")
+			}
+			if err := db.readPage(overflow, next); err != nil {
+				return nil, err
+			}
+			next = binary.BigEndian.Uint32(overflow)
+			payload = append(payload, overflow[4:min(db.usable, 4+int(payloadSize)-len(payload))]...)
+		}
+	}
+
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || int(headerSize) > len(payload) {
+		return nil, errors.New("corrupted SQLite record")
+	}
+	var values [][]byte
+	data := int(headerSize)
+	for position := n; position < int(headerSize) && len(values) < columns; position += n {
+		var serialType uint64
+		serialType, n = sqliteVarint(payload[position:headerSize])
+		if n == 0 {
+			return nil, errors.New("corrupted SQLite record")
+		}
+		size := sqliteValueSize(serialType)
+		if data+size > len(payload) {
+			return nil, errors.New("SQLite record too large")
+		}
+		values = append(values, payload[data:data+size])
+		data += size
+	}
+	return values, nil
+}
+
+// localPayload is how many bytes of a row of payloadSize bytes are stored on
+// a table leaf page, the rest going to overflow pages.
+func (db *sqliteDB) localPayload(payloadSize int) int {
+	maxLocal := db.usable - 35
+	if payloadSize <= maxLocal {
+		return payloadSize
+	}
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (payloadSize-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		return minLocal
+	}
+	return local
+}
+
+// sqliteVarint decodes a SQLite variable-length integer, and returns how many
+// bytes it takes, 0 if b is too short.
+func sqliteVarint(b []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(b) && i < 9; i++ {
+		if i == 8 {
+			return value<<8 | uint64(b[i]), 9
+		}
+		value = value<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// sqliteValueSize is the size of a column value of the given serial type.
+func sqliteValueSize(serialType uint64) int {
+	switch {
+	case serialType >= 12:
+		return int(serialType-12) / 2
+	case serialType == 5:
+		return 6
+	case serialType == 6 || serialType == 7:
+		return 8
+	case serialType >= 1 && serialType <= 4:
+		return int(serialType)
+	}
+	return 0 // NULL, and the constants 0 and 1
+}
+
+// sqliteInt decodes a big-endian two's complement integer column. The
+// constants 0 and 1 have no bytes and decode as 0: neither is the root page
+// of a table, page 1 being the schema's.
+func sqliteInt(b []byte) int64 {
+	var value int64
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		value = -1
+	}
+	for _, c := range b {
+		value = value<<8 | int64(c)
+	}
+	return value
+}
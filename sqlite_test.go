@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The rpm databases of testdata/packages were made with SQLite, with the
+// tables of rpm. The fedora one has 512-byte pages, so that its schema and
+// Packages tables span several levels of pages, and some of its rows, one of
+// the schema's included, overflow. The fedora-dnf one was copied with its
+// write-ahead log, before SQLite copied the log back: the file has 40
+// packages, the log 25 more, 5 deleted and a Sigmd5 table. A frame of random
+// bytes follows, as if a transaction was being written.
+func TestSQLiteCountRows(t *testing.T) {
+	fedora := filepath.Join("testdata", "packages", "fedora", "usr", "lib", "sysimage", "rpm", "rpmdb.sqlite")
+	dnf := filepath.Join("testdata", "packages", "fedora-dnf", "usr", "lib", "sysimage", "rpm", "rpmdb.sqlite")
+	for _, test := range []struct {
+		file  string
+		table string
+		want  int
+		err   bool
+	}{
+		{fedora, "Packages", 150, false},
+		{fedora, "Name", 150, false},
+		{fedora, "Basenames", 0, false},
+		{fedora, "Changelogs_of_every_installed_package_kept_for_the_overflow_test", 2, false},
+		{fedora, "Name_key_idx", 0, true},
+		{fedora, "Files", 0, true},
+		{dnf, "Packages", 60, false},
+		{dnf, "Name", 40, false},
+		{dnf, "Sigmd5", 3, false},
+		{filepath.Join("testdata", "packages", "rhel", "var", "lib", "rpm", "rpmdb.sqlite"), "Packages", 3, false},
+		{filepath.Join("testdata", "packages", "broken-rpm", "var", "lib", "rpm", "rpmdb.sqlite"), "Packages", 0, true},
+		{filepath.Join("testdata", "packages", "missing.sqlite"), "Packages", 0, true},
+	} {
+		count, err := countSQLiteRows(test.file, test.table)
+		if count != test.want || (err != nil) != test.err {
+			t.Errorf("%s in %s: got %d, %v, want %d (error: %v)", test.table, test.file, count, err, test.want, test.err)
+		}
+	}
+}
+
+func TestSQLiteVarint(t *testing.T) {
+	for _, test := range []struct {
+		bytes []byte
+		value uint64
+		size  int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f, 0xff}, 127, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0x82, 0xb5, 0x1a}, 39578, 3},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1<<64 - 1, 9},
+		{[]byte{0x81}, 0, 0},
+		{nil, 0, 0},
+	} {
+		if value, size := sqliteVarint(test.bytes); value != test.value || size != test.size {
+			t.Errorf("sqliteVarint(% x) = %d, %d, want %d, %d", test.bytes, value, size, test.value, test.size)
+		}
+	}
+}
+
+func TestSQLiteWithoutWAL(t *testing.T) {
+	// The same database, its log not written yet or already copied back.
+	dir := t.TempDir()
+	content, err := os.ReadFile(filepath.Join("testdata", "packages", "fedora-dnf", "usr", "lib", "sysimage", "rpm", "rpmdb.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "rpmdb.sqlite")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, wal := range []string{"", "garbage"} {
+		if wal != "" {
+			if err := os.WriteFile(file+"-wal", []byte(wal), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if count, err := countSQLiteRows(file, "Packages"); count != 40 || err != nil {
+			t.Errorf("with the log %q: got %d, %v, want the 40 packages of the file", wal, count, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// pageKeyHints are the key hints shown in the footer for each page.
+var pageKeyHints = map[string]string{
+	"dashboard": "q quit  %s tabs  s settings  h help  a alerts  f fleet  p pause  +/- interval",
+	"settings":  "ESC/s back  TAB/arrows navigate  q quit",
+	"help":      "ESC/h back  q quit",
+	"alerts":    "ESC/a back  q quit",
+	"fleet":     "ESC/f back  Enter open host  q quit",
+}
+
+// statusLine is the footer shown under every page.
+type statusLine struct {
+	view     *tview.TextView
+	pages    *tview.Pages
+	refresh  *refreshControl
+	alerts   *alertEngine
+	tabs     *tabSet
+	hostname string
+	// clock gives the time shown first in the footer.
+	clock func() time.Time
+
+	mu      sync.Mutex
+	message string
+}
+
+func newStatusLine(pages *tview.Pages, tabs *tabSet, refresh *refreshControl, alerts *alertEngine, hostname string) *statusLine {
+	view := tview.NewTextView()
+	view.SetDynamicColors(true)
+	view.SetWrap(false)
+	return &statusLine{
+		view:     view,
+		pages:    pages,
+		refresh:  refresh,
+		alerts:   alerts,
+		tabs:     tabs,
+		hostname: hostname,
+		clock:    time.Now,
+	}
+}
+
+// SetMessage sets the last alert or error message shown at the end of the
+// footer.
+func (status *statusLine) SetMessage(message string) {
+	status.mu.Lock()
+	status.message = message
+	status.mu.Unlock()
+}
+
+// Render rebuilds the footer text. It touches the pages, so it must run on
+// the application's goroutine, e.g. through QueueUpdateDraw.
+func (status *statusLine) Render(theme *Theme) {
+	interval, paused := status.refresh.State()
+	var state string
+	if paused {
+		state = fmt.Sprintf("[%s]%s[-]", theme.BarYellow.TrueColor().String(), tr("PAUSED"))
+	} else {
+		state = fmt.Sprintf("[%s]%s[-]", theme.BarGreen.TrueColor().String(), tr("LIVE"))
+	}
+	activeAlerts := status.alerts.ActiveCount()
+	alertsText := fmt.Sprintf(tr("%d alerts"), activeAlerts)
+	if activeAlerts > 0 {
+		alertsText = fmt.Sprintf("[%s]%s[-]", theme.BarRed.TrueColor().String(), alertsText)
+	}
+	currentPage, _ := status.pages.GetFrontPage()
+	keyHints := tr(pageKeyHints[currentPage])
+	if currentPage == "dashboard" {
+		// The number of tabs depends on what the system supports.
+		keyHints = fmt.Sprintf(keyHints, strings.NewReplacer("'", "", " ", "").Replace(status.tabs.KeyRange()))
+	}
+
+	status.mu.Lock()
+	message := status.message
+	status.mu.Unlock()
+
+	parts := []string{
+		formatTime(status.clock(), true),
+		state,
+		tr("Refresh: ") + interval.String(),
+		alertsText,
+		theme.Name,
+		status.hostname,
+		tview.Escape(keyHints),
+	}
+	if message != "" {
+		parts = append(parts, tview.Escape(message))
+	}
+	status.view.SetText(" " + strings.Join(parts, " | "))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// tabSet is the tab bar of the dashboard page and the pages of its tabs.
+type tabSet struct {
+	app     *tview.Application
+	bar     *tview.TextView
+	pages   *tview.Pages
+	layout  *tview.Flex
+	names   []string
+	focus   []tview.Primitive
+	grids   []*tview.Grid
+	current int
+}
+
+func newTabSet(app *tview.Application) *tabSet {
+	tabs := &tabSet{app: app}
+	tabs.bar = tview.NewTextView()
+	tabs.bar.SetDynamicColors(true)
+	tabs.bar.SetRegions(true)
+	tabs.bar.SetWrap(false)
+	tabs.pages = tview.NewPages()
+	tabs.layout = tview.NewFlex().SetDirection(tview.FlexRow)
+	tabs.layout.AddItem(tabs.bar, 1, 0, false)
+	tabs.layout.AddItem(tabs.pages, 0, 1, true)
+	return tabs
+}
+
+// Add appends a tab. focus is the widget that gets the keyboard (e.g. for
+// scrolling) while the tab is shown.
+func (tabs *tabSet) Add(name string, grid *tview.Grid, focus tview.Primitive) {
+	tabs.pages.AddPage(name, grid, true, len(tabs.names) == 0)
+	tabs.names = append(tabs.names, name)
+	tabs.focus = append(tabs.focus, focus)
+	tabs.grids = append(tabs.grids, grid)
+
+	var barText string
+	for i, tabName := range tabs.names {
+		barText += fmt.Sprintf(`["%d"] %s %s [""] `, i, tabKey(i), tview.Escape(tr(tabName)))
+	}
+	tabs.bar.SetText(barText)
+}
+
+// Switch shows the i-th tab and returns false if there is no such tab.
+func (tabs *tabSet) Switch(i int) bool {
+	if i < 0 || i >= len(tabs.names) {
+		return false
+	}
+	tabs.current = i
+	tabs.pages.SwitchToPage(tabs.names[i])
+	tabs.bar.Highlight(strconv.Itoa(i))
+	tabs.app.SetFocus(tabs.focus[i])
+	return true
+}
+
+// tabKey is the key switching to the i-th tab: '1' to '9', then '0' for the
+// tenth. Tabs after it have no key.
+func tabKey(i int) string {
+	switch {
+	case i < 9:
+		return strconv.Itoa(i + 1)
+	case i == 9:
+		return "0"
+	}
+	return ""
+}
+
+// KeyRange is the keys switching between the tabs, e.g. "'1'-'7'", or
+// "'1'-'9', '0'" with ten tabs or more.
+func (tabs *tabSet) KeyRange() string {
+	if len(tabs.names) >= 10 {
+		return "'1'-'9', '0'"
+	}
+	return fmt.Sprintf("'1'-'%d'", len(tabs.names))
+}
+
+func (tabs *tabSet) Current() string {
+	return tabs.names[tabs.current]
+}
+
+func (tabs *tabSet) applyTheme(theme *Theme) {
+	tabs.bar.SetTextColor(theme.InfoPanel.TitleColor)
+	tabs.bar.SetBackgroundColor(theme.Backgroundcolor)
+	for _, grid := range tabs.grids {
+		grid.SetBackgroundColor(theme.Backgroundcolor)
+	}
+}
+
+var processColumns = []struct {
+	name      string
+	align     int
+	expansion int
+}{
+	{"PID", tview.AlignRight, 0},
+	{"User", tview.AlignLeft, 0},
+	{"CPU%", tview.AlignRight, 0},
+	{"MEM%", tview.AlignRight, 0},
+	{"RSS", tview.AlignRight, 0},
+	{"Threads", tview.AlignRight, 0},
+	{"State", tview.AlignLeft, 0},
+	{"Command", tview.AlignLeft, 1},
+}
+
+func (d *dashboard) updateProcesses(theme *Theme, processes []ProcessSample, unavailable string) {
+	table := d.processTable
+	for column, header := range processColumns {
+		table.SetCell(0, column, tview.NewTableCell(tr(header.name)).
+			SetAlign(header.align).
+			SetTextColor(theme.ProcPanel.TitleColor).
+			SetBackgroundColor(theme.ProcPanel.BackGroundColor).
+			SetAttributes(tcell.AttrBold).
+			SetSelectable(false))
+	}
+	for row, proc := range processes {
+		cpuColor := barColor(theme, proc.CPUPercent)
+		values := []string{
+			strconv.Itoa(int(proc.PID)),
+			tview.Escape(proc.User),
+			fmt.Sprintf("%s%s[-]", cpuColor, formatNumber(proc.CPUPercent, 1)),
+			formatNumber(float64(proc.MemPercent), 1),
+			formatBytes(proc.RSS),
+			strconv.Itoa(int(proc.Threads)),
+			proc.Status,
+			tview.Escape(proc.Command),
+		}
+		for column, value := range values {
+			table.SetCell(row+1, column, tview.NewTableCell(value).
+				SetAlign(processColumns[column].align).
+				SetExpansion(processColumns[column].expansion).
+				SetTextColor(theme.ProcPanel.TextColor).
+				SetBackgroundColor(theme.ProcPanel.BackGroundColor))
+		}
+	}
+	for row := table.GetRowCount() - 1; row > len(processes); row-- {
+		table.RemoveRow(row)
+	}
+	table.SetTitle(fmt.Sprintf(tr("Processes (%d busiest)"), len(processes)))
+	if unavailable != "" {
+		// The table has no room for a message, the title tells why it is empty.
+		table.SetTitle(tr("Processes") + " - " + orUnavailable(theme, unavailable, ""))
+	}
+}
+
+func renderNetwork(theme *Theme, interfaces []NetSample) string {
+	if len(interfaces) == 0 {
+		return tr("No network interfaces found.")
+	}
+	var text string
+	for _, iface := range interfaces {
+		state := fmt.Sprintf("[%s]%s[-]", theme.BarRed.TrueColor().String(), tr("down"))
+		if iface.Up {
+			state = fmt.Sprintf("[%s]%s[-]", theme.BarGreen.TrueColor().String(), tr("up"))
+		}
+		text += fmt.Sprintf("%s (%s)\n", iface.Name, state)
+		if len(iface.Addrs) > 0 {
+			text += fmt.Sprintf("  "+tr("Addresses: %s")+"\n", strings.Join(iface.Addrs, ", "))
+		}
+		text += fmt.Sprintf("  "+tr("Download: %s/s (total %s)")+"\n", formatBytes(uint64(iface.RecvRate)), formatBytes(iface.BytesRecv))
+		text += fmt.Sprintf("  "+tr("Upload: %s/s (total %s)")+"\n", formatBytes(uint64(iface.SendRate)), formatBytes(iface.BytesSent))
+		if iface.Errors > 0 || iface.Drops > 0 {
+			text += fmt.Sprintf("  [%s]"+tr("Errors: %d, dropped: %d")+"[-]\n", theme.BarYellow.TrueColor().String(), iface.Errors, iface.Drops)
+		}
+		text += "\n"
+	}
+	return text
+}
+
+func renderDiskIO(diskIO []DiskIOSample) string {
+	if len(diskIO) == 0 {
+		return tr("No disk I/O counters available.")
+	}
+	var text string
+	for _, io := range diskIO {
+		text += fmt.Sprintf("%s: "+tr("read %s/s, write %s/s (total read %s, written %s)")+"\n", io.Name, formatBytes(uint64(io.ReadRate)), formatBytes(uint64(io.WriteRate)), formatBytes(io.ReadBytes), formatBytes(io.WriteBytes))
+	}
+	return text
+}
+
+func renderHardware(theme *Theme, staticInfo *StaticInfo, sample *Sample) string {
+	text := "[::b]CPU[::-]\n"
+	if reason := staticInfo.Unavailable["cpu"]; reason != "" {
+		text += orUnavailable(theme, reason, "") + "\n"
+	} else {
+		text += fmt.Sprintf(tr("Model: %s")+"\n", tview.Escape(staticInfo.CPUModel))
+		text += fmt.Sprintf(tr("Vendor: %s, family %s")+"\n", tview.Escape(staticInfo.CPUVendor), tview.Escape(staticInfo.CPUFamily))
+		cores := fmt.Sprintf("%d/%d", staticInfo.CPUPhysCore, staticInfo.CPULogCore)
+		text += fmt.Sprintf(tr("Cores physical/logical: %s")+"\n", orUnavailable(theme, staticInfo.Unavailable["cores"], cores))
+		text += fmt.Sprintf(tr("Frequency: %.0f MHz")+"\n", staticInfo.CPUMHz)
+		text += fmt.Sprintf(tr("Cache: %d KB")+"\n", staticInfo.CPUCacheSize)
+	}
+	text += "\n[::b]" + tr("Memory") + "[::-]\n"
+	text += orUnavailable(theme, sample.Unavailable["memory"], fmt.Sprintf(tr("Total: %s"), formatBytes(sample.MemTotal))) + "\n"
+	text += fmt.Sprintf(tr("Swap: %s")+"\n", orUnavailable(theme, sample.Unavailable["swap"], formatBytes(sample.SwapTotal)))
+	text += "\n[::b]" + tr("Cgroup") + "[::-]\n"
+	text += orUnavailable(theme, sample.Unavailable["cgroup"], renderCgroup(sample.Cgroup))
+	text += "\n[::b]" + tr("Host") + "[::-]\n"
+	text += fmt.Sprintf(tr("Hostname: %s")+"\n", orUnavailable(theme, staticInfo.Unavailable["host"], staticInfo.Hostname))
+	text += fmt.Sprintf(tr("Architecture: %s")+"\n", orUnavailable(theme, staticInfo.Unavailable["kernel"], staticInfo.KernelArch))
+	text += fmt.Sprintf(tr("Kernel: %s")+"\n", orUnavailable(theme, staticInfo.Unavailable["kernel"], staticInfo.KernelVersion))
+	if staticInfo.Virtualization != "" {
+		text += fmt.Sprintf(tr("Virtualization: %s (%s)")+"\n", staticInfo.Virtualization, staticInfo.VirtualizationRole)
+	} else {
+		text += tr("Virtualization: none detected") + "\n"
+	}
+	if staticInfo.BootTime > 0 {
+		text += fmt.Sprintf(tr("Booted: %s")+"\n", formatTime(time.Unix(int64(staticInfo.BootTime), 0), true))
+	}
+	return text
+}
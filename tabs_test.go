@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestRenderHardwareEscaped(t *testing.T) {
+	useDefaultPreferences(t)
+	staticInfo := fakeStaticInfo()
+	staticInfo.CPUModel = "QEMU Virtual CPU [host]"
+	text := renderHardware(&defaultTheme, &staticInfo, fakeSample(0))
+	shown := tview.NewTextView().SetDynamicColors(true).SetText(text).GetText(true)
+	if !strings.Contains(shown, "Model: QEMU Virtual CPU [host]\n") {
+		t.Errorf("the CPU model isn't shown as is:\n%s", shown)
+	}
+}